@@ -0,0 +1,226 @@
+package wtdetach
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// BatchResult is the outcome of detaching one branch of a batch
+type BatchResult struct {
+	Branch     string
+	Result     *Result
+	Err        error
+	RolledBack bool
+}
+
+// IsBranchPattern reports whether a branch argument is a glob pattern
+func IsBranchPattern(arg string) bool {
+	return strings.ContainsAny(arg, "*?[")
+}
+
+// ExpandBranches resolves branch arguments to branch names. Glob patterns are
+// matched against the branches checked out in other worktrees; plain names
+// are kept as they are. Duplicates are dropped.
+func (d *Detacher) ExpandBranches(args []string) ([]string, error) {
+	var checkedOut []string
+	for _, arg := range args {
+		if !IsBranchPattern(arg) {
+			continue
+		}
+		if _, err := path.Match(arg, ""); err != nil {
+			return nil, fmt.Errorf("invalid branch pattern '%s': %w", arg, err)
+		}
+		if checkedOut == nil {
+			currentPath, err := d.GetCurrentWorktreePath()
+			if err != nil {
+				return nil, err
+			}
+			worktrees, err := d.ListWorktrees()
+			if err != nil {
+				return nil, err
+			}
+			checkedOut = []string{}
+			for _, wt := range worktrees {
+				if wt.Branch != "" && wt.Path != currentPath {
+					checkedOut = append(checkedOut, wt.Branch)
+				}
+			}
+		}
+	}
+
+	var branches []string
+	seen := map[string]bool{}
+	add := func(branch string) {
+		if !seen[branch] {
+			seen[branch] = true
+			branches = append(branches, branch)
+		}
+	}
+	for _, arg := range args {
+		if !IsBranchPattern(arg) {
+			add(arg)
+			continue
+		}
+		matched := false
+		for _, branch := range checkedOut {
+			if ok, _ := path.Match(arg, branch); ok {
+				add(branch)
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("no branch checked out in another worktree matches '%s'", arg)
+		}
+	}
+	return branches, nil
+}
+
+// DetachAll detaches several branches in order. If one fails, the branches
+// detached before it are reverted again, unless opts.KeepGoing is set, in
+// which case the remaining branches are still detached and all errors are
+// returned together.
+func (d *Detacher) DetachAll(branches []string, opts *Options) ([]*BatchResult, error) {
+	var results []*BatchResult
+	var errs []error
+	for _, branch := range branches {
+		result, err := d.Detach(branch, opts)
+		results = append(results, &BatchResult{Branch: branch, Result: result, Err: err})
+		if err == nil {
+			continue
+		}
+		err = fmt.Errorf("%s: %w", branch, err)
+		if opts.KeepGoing {
+			errs = append(errs, err)
+			continue
+		}
+		if rbErr := d.rollbackBatch(results, opts); rbErr != nil {
+			return results, errors.Join(err, rbErr)
+		}
+		return results, err
+	}
+	return results, errors.Join(errs...)
+}
+
+// rollbackBatch reverts the detached branches of a batch in reverse order
+func (d *Detacher) rollbackBatch(results []*BatchResult, opts *Options) error {
+	if opts.DryRun {
+		return nil
+	}
+	var errs []error
+	for i := len(results) - 1; i >= 0; i-- {
+		r := results[i]
+		// A detach whose checkout failed has a result too
+		if r.Result == nil || r.Result.TempBranch == "" {
+			continue
+		}
+		revertOpts := &Options{Yes: true, Force: opts.Force, TempBranch: r.Result.TempBranch}
+		if _, err := d.Revert(r.Branch, revertOpts); err != nil {
+			errs = append(errs, fmt.Errorf("failed to roll back %s: %w", r.Branch, err))
+			continue
+		}
+		r.RolledBack = true
+	}
+	return errors.Join(errs...)
+}
+
+// BranchEntry is a branch read from a branch list, with the worktree it is
+// expected in, if given
+type BranchEntry struct {
+	Branch   string
+	Worktree string
+}
+
+// EntryResult is the outcome of applying a branch list entry, reported as a
+// JSON line
+type EntryResult struct {
+	Branch     string `json:"branch"`
+	Worktree   string `json:"worktree,omitempty"`
+	Action     string `json:"action"`
+	OK         bool   `json:"ok"`
+	TempBranch string `json:"temp_branch,omitempty"`
+	Message    string `json:"message,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ParseBranchList parses a list of branches separated by newlines, or by NUL
+// if the list contains any. A worktree path or name may follow the branch
+// after whitespace. Empty entries are ignored.
+func ParseBranchList(data []byte) []BranchEntry {
+	sep := "\n"
+	if strings.Contains(string(data), "\x00") {
+		sep = "\x00"
+	}
+
+	var entries []BranchEntry
+	for _, line := range strings.Split(string(data), sep) {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		branch, worktree := line, ""
+		if i := strings.IndexAny(line, " \t"); i >= 0 {
+			branch, worktree = line[:i], line[i+1:]
+		}
+		entries = append(entries, BranchEntry{
+			Branch:   branch,
+			Worktree: strings.TrimSpace(worktree),
+		})
+	}
+	return entries
+}
+
+// ApplyEntry detaches or reverts the branch of a branch list entry, depending
+// on opts.Revert
+func (d *Detacher) ApplyEntry(entry BranchEntry, opts Options) *EntryResult {
+	res := &EntryResult{Branch: entry.Branch, Worktree: entry.Worktree, Action: "detach"}
+	opts.Worktree = entry.Worktree
+
+	var result *Result
+	var err error
+	if opts.Revert {
+		res.Action = "revert"
+		result, err = d.revertEntry(entry, &opts)
+	} else {
+		result, err = d.Detach(entry.Branch, &opts)
+	}
+	if err == nil && result.Conflict != nil {
+		err = result.Conflict
+	}
+	if err != nil {
+		res.Error = err.Error()
+		if result != nil {
+			res.TempBranch = result.TempBranch
+		}
+		return res
+	}
+
+	res.OK = true
+	res.TempBranch = result.TempBranch
+	res.Message = result.Message
+	if result.WorktreePath != "" {
+		res.Worktree = result.WorktreePath
+	}
+	return res
+}
+
+// revertEntry reverts a branch list entry, looking up the temporary branch
+// from the worktree if one is given
+func (d *Detacher) revertEntry(entry BranchEntry, opts *Options) (*Result, error) {
+	if entry.Worktree != "" && opts.TempBranch == "" {
+		wt, err := d.ResolveBranchWorktree(entry.Worktree)
+		if err != nil {
+			return nil, err
+		}
+		tmpBranch, origin, err := d.TempBranchOf(wt)
+		if err != nil {
+			return nil, err
+		}
+		if origin != entry.Branch {
+			return nil, fmt.Errorf("worktree %s is on temp branch '%s' of '%s', not of '%s'", wt.Path, tmpBranch, origin, entry.Branch)
+		}
+		opts.TempBranch = tmpBranch
+	}
+	return d.Revert(entry.Branch, opts)
+}
@@ -0,0 +1,134 @@
+package wtdetach
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIntegration_DetachAll(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	tempDir := resolvePath(t, t.TempDir())
+	for _, name := range []string{"release/1.0", "release/2.0", "feature-batch"} {
+		createBranch(t, repoDir, name)
+		createWorktree(t, repoDir, filepath.Join(tempDir, filepath.Base(name)), name)
+	}
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+
+	// Test: patterns match branches checked out in other worktrees
+	branches, err := d.ExpandBranches([]string{"release/*", "feature-batch", "release/1.0"})
+	if err != nil {
+		t.Fatalf("ExpandBranches failed: %v", err)
+	}
+	if len(branches) != 3 || branches[0] != "release/1.0" || branches[1] != "release/2.0" || branches[2] != "feature-batch" {
+		t.Errorf("unexpected branches: %v", branches)
+	}
+	if _, err := d.ExpandBranches([]string{"hotfix/*"}); err == nil {
+		t.Error("ExpandBranches should fail for a pattern without matches")
+	}
+
+	// Test: a failure rolls back the branches detached before it
+	runGit(t, repoDir, "branch", "release/2.0__wt_detach")
+	results, err := d.DetachAll(branches, &Options{Yes: true})
+	if err == nil {
+		t.Fatal("DetachAll should fail when a temp branch already exists")
+	}
+	if len(results) != 2 || !results[0].RolledBack || results[1].Err == nil {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	if branch := getCurrentBranch(t, filepath.Join(tempDir, "1.0")); branch != "release/1.0" {
+		t.Errorf("release/1.0 should be rolled back, got %s", branch)
+	}
+	if branchExistsInRepo(t, repoDir, "release/1.0__wt_detach") {
+		t.Error("rolled back temp branch should be deleted")
+	}
+
+	// Test: --keep-going detaches the rest
+	results, err = d.DetachAll(branches, &Options{Yes: true, KeepGoing: true})
+	if err == nil {
+		t.Fatal("DetachAll should report the failed branch")
+	}
+	if len(results) != 3 || results[0].Err != nil || results[1].Err == nil || results[2].Err != nil {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	if branch := getCurrentBranch(t, filepath.Join(tempDir, "1.0")); branch != "release/1.0__wt_detach" {
+		t.Errorf("release/1.0 should be detached, got %s", branch)
+	}
+	if branch := getCurrentBranch(t, filepath.Join(tempDir, "feature-batch")); branch != "feature-batch__wt_detach" {
+		t.Errorf("feature-batch should be detached, got %s", branch)
+	}
+}
+
+func TestParseBranchList(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []BranchEntry
+	}{
+		{
+			name:     "newline separated",
+			input:    "feature-a\n\nfeature-b  ../wt-b\n",
+			expected: []BranchEntry{{Branch: "feature-a"}, {Branch: "feature-b", Worktree: "../wt-b"}},
+		},
+		{
+			name:     "NUL separated with spaces in the worktree",
+			input:    "feature-a\tmy worktree\x00feature-b\x00",
+			expected: []BranchEntry{{Branch: "feature-a", Worktree: "my worktree"}, {Branch: "feature-b"}},
+		},
+		{
+			name:     "empty",
+			input:    "\n",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := ParseBranchList([]byte(tt.input))
+			if len(entries) != len(tt.expected) {
+				t.Fatalf("expected %d entries, got %+v", len(tt.expected), entries)
+			}
+			for i, e := range entries {
+				if e != tt.expected[i] {
+					t.Errorf("entry[%d]: expected %+v, got %+v", i, tt.expected[i], e)
+				}
+			}
+		})
+	}
+}
+
+func TestIntegration_ApplyEntry(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature-entry")
+	worktreeDir := filepath.Join(resolvePath(t, t.TempDir()), "worktree-entry")
+	createWorktree(t, repoDir, worktreeDir, "feature-entry")
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+
+	res := d.ApplyEntry(BranchEntry{Branch: "missing"}, Options{Yes: true})
+	if res.OK || res.Error == "" || res.Action != "detach" {
+		t.Errorf("expected a failed detach, got %+v", res)
+	}
+
+	res = d.ApplyEntry(BranchEntry{Branch: "feature-entry", Worktree: "worktree-entry"}, Options{Yes: true})
+	if !res.OK || res.TempBranch != "feature-entry__wt_detach" || res.Worktree != worktreeDir {
+		t.Fatalf("unexpected detach result: %+v", res)
+	}
+
+	res = d.ApplyEntry(BranchEntry{Branch: "feature-entry", Worktree: "worktree-entry"}, Options{Yes: true, Revert: true})
+	if !res.OK || res.Action != "revert" {
+		t.Fatalf("unexpected revert result: %+v", res)
+	}
+	if branch := getCurrentBranch(t, worktreeDir); branch != "feature-entry" {
+		t.Errorf("worktree should be back on feature-entry, got %s", branch)
+	}
+}
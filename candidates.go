@@ -0,0 +1,112 @@
+package wtdetach
+
+import (
+	"strings"
+	"time"
+)
+
+// Candidate is a branch that can be detached or reverted, as offered when no
+// branch is given
+type Candidate struct {
+	Branch string
+	// TempBranch is the temporary branch of a detached branch
+	TempBranch string
+	// Worktree is the worktree the branch, or its temporary branch, is
+	// checked out in. It is empty for a temporary branch not checked out.
+	Worktree string
+	Subject  string
+	Dirty    bool
+	// Expires is the deadline of a detached branch, zero if there is none
+	Expires time.Time
+}
+
+// Overdue returns how long ago the detach of the branch expired, zero if it
+// has not
+func (c *Candidate) Overdue(now time.Time) time.Duration {
+	if c.Expires.IsZero() || c.Expires.After(now) {
+		return 0
+	}
+	return now.Sub(c.Expires)
+}
+
+// DetachCandidates returns the branches checked out in worktrees other than
+// the current one, except temporary branches
+func (d *Detacher) DetachCandidates() ([]Candidate, error) {
+	currentPath, _ := d.GetCurrentWorktreePath()
+	worktrees, err := d.ListWorktrees()
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []Candidate
+	for _, wt := range worktrees {
+		if wt.Branch == "" || wt.Path == currentPath {
+			continue
+		}
+		if _, err := d.OriginOf(wt.Branch); err == nil {
+			continue
+		}
+		candidates = append(candidates, Candidate{
+			Branch:   wt.Branch,
+			Worktree: wt.Path,
+			Subject:  d.lastSubject(wt.Branch),
+			Dirty:    d.HasUncommittedChanges(wt.Path),
+		})
+	}
+	return candidates, nil
+}
+
+// RevertCandidates returns the detached branches
+func (d *Detacher) RevertCandidates() ([]Candidate, error) {
+	states, err := d.ListTempBranches()
+	if err != nil {
+		return nil, err
+	}
+	worktrees, err := d.ListWorktrees()
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]Candidate, 0, len(states))
+	for _, st := range states {
+		c := Candidate{
+			Branch:     st.Origin,
+			TempBranch: st.TempBranch,
+			Subject:    d.lastSubject(st.TempBranch),
+			Expires:    st.Expires,
+		}
+		if wt := FindWorktreeByBranch(worktrees, st.TempBranch, ""); wt != nil {
+			c.Worktree = wt.Path
+			c.Dirty = d.HasUncommittedChanges(wt.Path)
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, nil
+}
+
+// lastSubject returns the subject of the last commit on a branch
+func (d *Detacher) lastSubject(branch string) string {
+	subject, _ := d.git.Run("log", "-1", "--format=%s", "refs/heads/"+branch)
+	return subject
+}
+
+// FilterCandidates returns the candidates whose branch or worktree contains
+// every word of query, ignoring case
+func FilterCandidates(candidates []Candidate, query string) []Candidate {
+	words := strings.Fields(strings.ToLower(query))
+	var matches []Candidate
+	for _, c := range candidates {
+		text := strings.ToLower(c.Branch + " " + c.TempBranch + " " + c.Worktree)
+		matched := true
+		for _, w := range words {
+			if !strings.Contains(text, w) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			matches = append(matches, c)
+		}
+	}
+	return matches
+}
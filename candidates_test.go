@@ -0,0 +1,139 @@
+package wtdetach
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFilterCandidates(t *testing.T) {
+	candidates := []Candidate{
+		{Branch: "feature-login", Worktree: "/src/repo-login"},
+		{Branch: "feature-search", Worktree: "/src/repo-search"},
+		{Branch: "fix-typo", TempBranch: "fix-typo__wt_detach", Worktree: "/src/Review"},
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"feature-login", "feature-search", "fix-typo"}},
+		{"feature", []string{"feature-login", "feature-search"}},
+		{"feature search", []string{"feature-search"}},
+		{"review", []string{"fix-typo"}},
+		{"wt_detach", []string{"fix-typo"}},
+		{"nothing", nil},
+	}
+
+	for _, tt := range tests {
+		var got []string
+		for _, c := range FilterCandidates(candidates, tt.query) {
+			got = append(got, c.Branch)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("FilterCandidates(%q) = %v, want %v", tt.query, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("FilterCandidates(%q) = %v, want %v", tt.query, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestCandidateOverdue(t *testing.T) {
+	now := time.Date(2026, 1, 31, 18, 0, 0, 0, time.UTC)
+	tests := []struct {
+		expires time.Time
+		want    time.Duration
+	}{
+		{time.Time{}, 0},
+		{now.Add(time.Hour), 0},
+		{now.Add(-90 * time.Minute), 90 * time.Minute},
+	}
+	for _, tt := range tests {
+		c := Candidate{Branch: "feature", Expires: tt.expires}
+		if got := c.Overdue(now); got != tt.want {
+			t.Errorf("Overdue() with expiry %v = %v, want %v", tt.expires, got, tt.want)
+		}
+	}
+}
+
+func TestIntegration_Candidates(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature-clean")
+	createBranch(t, repoDir, "feature-dirty")
+	tmpDir := resolvePath(t, t.TempDir())
+	cleanDir := filepath.Join(tmpDir, "worktree-clean")
+	dirtyDir := filepath.Join(tmpDir, "worktree-dirty")
+	createWorktree(t, repoDir, cleanDir, "feature-clean")
+	createWorktree(t, repoDir, dirtyDir, "feature-dirty")
+	createUncommittedChange(t, dirtyDir)
+	runGit(t, cleanDir, "commit", "--allow-empty", "-m", "clean work")
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+	candidates, err := d.DetachCandidates()
+	if err != nil {
+		t.Fatalf("DetachCandidates failed: %v", err)
+	}
+	if len(candidates) != 2 {
+		t.Fatalf("expected 2 candidates, got %+v", candidates)
+	}
+	clean, dirty := candidates[0], candidates[1]
+	if clean.Branch != "feature-clean" || clean.Worktree != cleanDir || clean.Subject != "clean work" || clean.Dirty {
+		t.Errorf("unexpected candidate: %+v", clean)
+	}
+	if dirty.Branch != "feature-dirty" || !dirty.Dirty {
+		t.Errorf("unexpected candidate: %+v", dirty)
+	}
+
+	// Test: a detached branch is offered for revert, not for detach
+	if _, err := d.Detach("feature-clean", &Options{Yes: true}); err != nil {
+		t.Fatalf("Detach failed: %v", err)
+	}
+	candidates, err = d.DetachCandidates()
+	if err != nil {
+		t.Fatalf("DetachCandidates failed: %v", err)
+	}
+	if len(candidates) != 1 || candidates[0].Branch != "feature-dirty" {
+		t.Errorf("expected only feature-dirty, got %+v", candidates)
+	}
+
+	candidates, err = d.RevertCandidates()
+	if err != nil {
+		t.Fatalf("RevertCandidates failed: %v", err)
+	}
+	want := Candidate{
+		Branch:     "feature-clean",
+		TempBranch: "feature-clean__wt_detach",
+		Worktree:   cleanDir,
+		Subject:    "clean work",
+	}
+	if len(candidates) != 1 || candidates[0] != want {
+		t.Errorf("RevertCandidates() = %+v, want %+v", candidates, want)
+	}
+
+	// Test: the deadline of a detach comes along, so that overdue ones can
+	// be marked
+	if _, err := d.Revert("feature-clean", &Options{Yes: true}); err != nil {
+		t.Fatalf("Revert failed: %v", err)
+	}
+	expires := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if _, err := d.Detach("feature-clean", &Options{Yes: true, Expires: expires}); err != nil {
+		t.Fatalf("Detach failed: %v", err)
+	}
+	candidates, err = d.RevertCandidates()
+	if err != nil {
+		t.Fatalf("RevertCandidates failed: %v", err)
+	}
+	if len(candidates) != 1 || !candidates[0].Expires.Equal(expires) || candidates[0].Overdue(time.Now()) <= 0 {
+		t.Errorf("expected an overdue candidate expiring at %v, got %+v", expires, candidates)
+	}
+}
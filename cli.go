@@ -1,30 +1,172 @@
 package wtdetach
 
 import (
-	"bufio"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"slices"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/alecthomas/kong"
 )
 
 // CLI defines the command-line interface
 type CLI struct {
-	Branch   string           `arg:"" optional:"" help:"Branch name to detach or revert."`
-	DryRun   bool             `help:"Show what would be done without making changes." short:"n"`
-	Revert   bool             `help:"Revert the temporary detach." short:"r"`
-	Force    bool             `help:"Force execution even with uncommitted changes." short:"f"`
-	Yes      bool             `help:"Skip confirmation prompt." short:"y"`
-	Checkout bool             `help:"Checkout the branch after detaching." short:"c"`
-	Init     string           `help:"Output shell completion script (bash, zsh, fish)." placeholder:"SHELL"`
-	Version  kong.VersionFlag `help:"Show version."`
-}
-
-// Run executes the CLI command
-func (c *CLI) Run() error {
-	if c.Init != "" {
-		script, err := CompletionScript(c.Init)
+	DryRun  bool             `help:"Show what would be done without making changes." short:"n" negatable:"" config:"dryRun"`
+	Force   bool             `help:"Force execution even with uncommitted changes." short:"f" negatable:"" config:"force"`
+	Yes     bool             `help:"Skip confirmation prompt." short:"y" negatable:"" config:"yes"`
+	Init    string           `help:"Output shell completion script (bash, zsh, fish)." placeholder:"SHELL"`
+	Version kong.VersionFlag `help:"Show version."`
+
+	Detach  DetachCmd  `cmd:"" default:"withargs" help:"Detach a branch checked out in another worktree, or revert it (default). Branches named like a command need the command name: detach <branch>."`
+	GC      GCCmd      `cmd:"" name:"gc" help:"Delete orphaned temp branches."`
+	Doctor  DoctorCmd  `cmd:"" help:"Diagnose and repair inconsistent detach state."`
+	Swap    SwapCmd    `cmd:"" help:"Swap branches between the current worktree and another one."`
+	Move    MoveCmd    `cmd:"" help:"Move a branch from another worktree to the current one for good."`
+	Exec    ExecCmd    `cmd:"" help:"Borrow a branch for the duration of a command, then revert."`
+	Expire  ExpireCmd  `cmd:"" help:"Revert detaches whose --for or --until deadline has passed."`
+	Refresh RefreshCmd `cmd:"" help:"Fast-forward temp branches to their original branch."`
+	Config  ConfigCmd  `cmd:"" help:"Show the effective configuration."`
+
+	// configured maps the flags given a default by ConfigResolver to where
+	// the default comes from
+	configured map[string]string
+}
+
+// DetachCmd detaches or reverts a branch
+type DetachCmd struct {
+	Branches   []string  `arg:"" optional:"" name:"branch" help:"Branch names or glob patterns to detach, or the branch to revert."`
+	Branch     string    `kong:"-"`
+	Revert     bool      `help:"Revert the temporary detach." short:"r"`
+	Checkout   bool      `help:"Checkout the branch after detaching." short:"c" xor:"target" negatable:"" config:"checkout"`
+	Track      bool      `help:"Copy the upstream tracking config of the branch to the temp branch." short:"t" negatable:"" config:"track"`
+	Give       string    `help:"Give the branch checked out here to another worktree, by path or name." placeholder:"WORKTREE" xor:"target,source"`
+	To         string    `help:"Checkout the branch after detaching in another worktree, by path, name, or glob." placeholder:"WORKTREE" xor:"target"`
+	Worktree   string    `help:"Detach or revert the branch of this worktree, by path or name." placeholder:"WORKTREE" short:"w" xor:"source"`
+	TempBranch string    `help:"Temp branch to revert, instead of looking it up." placeholder:"BRANCH"`
+	KeepGoing  bool      `help:"Keep detaching the remaining branches when one fails, instead of rolling back." negatable:"" config:"keepGoing"`
+	For        string    `help:"Revert the detach with the expire command after this long, e.g. 2h or 3d." placeholder:"DURATION" xor:"expiry"`
+	Until      string    `help:"Revert the detach with the expire command after this time, e.g. 18:00 or 2026-01-31." placeholder:"TIME" xor:"expiry"`
+	Expires    time.Time `kong:"-"`
+	Stdin      bool      `help:"Read branches to detach or revert from stdin, one per line or NUL-separated, each optionally followed by its worktree. Prints JSON lines."`
+}
+
+// GCCmd deletes orphaned temporary branches
+type GCCmd struct{}
+
+// DoctorCmd diagnoses and repairs inconsistent detach state
+type DoctorCmd struct {
+	Fix bool `help:"Apply the suggested fixes."`
+}
+
+// SwapCmd swaps branches between the current worktree and another one
+type SwapCmd struct {
+	Target string `arg:"" help:"Branch checked out in the other worktree, or its path or name."`
+}
+
+// MoveCmd permanently moves a branch to the current worktree
+type MoveCmd struct {
+	Branch   string `arg:"" help:"Branch name to move."`
+	Fallback string `help:"What the other worktree is switched to: detached, default, or a branch name (default: detached)." placeholder:"FALLBACK" config:"moveFallback"`
+}
+
+// ExecCmd borrows a branch for the duration of a command
+type ExecCmd struct {
+	Branch  string   `arg:"" help:"Branch name to borrow."`
+	Command []string `arg:"" passthrough:"" help:"Command to run, after --."`
+}
+
+// ExpireCmd reverts expired detaches
+type ExpireCmd struct{}
+
+// RefreshCmd fast-forwards temporary branches to their original branch
+type RefreshCmd struct {
+	Branch string `arg:"" optional:"" help:"Detached branch whose temp branch to refresh (default: all)."`
+}
+
+// ConfigCmd shows the effective configuration
+type ConfigCmd struct{}
+
+// BeforeResolve warns when the command given is also the name of a branch.
+// Such a branch can only be detached or reverted with the detach command
+// spelled out.
+func (cli *CLI) BeforeResolve(ctx *kong.Context) error {
+	cmd := ctx.Selected()
+	if cmd == nil || cmd.Name == "detach" {
+		return nil
+	}
+	if NewDetacher().BranchExists(cmd.Name) {
+		fmt.Fprintf(os.Stderr, "⚠ Warning: '%s' is also a branch; running the %s command\n  To detach or revert the branch, run: git wt-detach detach %s\n", cmd.Name, cmd.Name, cmd.Name)
+	}
+	return nil
+}
+
+// ConfigResolver returns a kong resolver giving the flags tagged
+// config:"<key>" their default from wt-detach.<key>. A flag is left alone
+// when the command line sets one it cannot be combined with.
+func (cli *CLI) ConfigResolver() kong.Resolver {
+	d := NewDetacher()
+	return kong.ResolverFunc(func(_ *kong.Context, parent *kong.Path, flag *kong.Flag) (any, error) {
+		key := flag.Tag.Get("config")
+		if key == "" || xorSet(parent, flag) {
+			return nil, nil
+		}
+		value, source, ok := d.LookupConfig(key)
+		if !ok {
+			return nil, nil
+		}
+		if flag.IsBool() {
+			b, err := ParseConfigBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("wt-detach.%s (%s): %w", key, source, err)
+			}
+			if !b {
+				return nil, nil
+			}
+			value = "true"
+		}
+		if cli.configured == nil {
+			cli.configured = map[string]string{}
+		}
+		cli.configured[flag.Name] = source
+		return value, nil
+	})
+}
+
+// xorSet reports whether the command line sets a flag that cannot be combined
+// with flag
+func xorSet(parent *kong.Path, flag *kong.Flag) bool {
+	for _, other := range parent.Flags {
+		if other == flag || !other.Set {
+			continue
+		}
+		for _, group := range other.Xor {
+			if slices.Contains(flag.Xor, group) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// newDetacher creates a Detacher configured from git config
+func newDetacher() (*Detacher, error) {
+	d := NewDetacher()
+	d.LoadSuffixFromConfig()
+	if err := d.LoadNameTemplateFromConfig(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Run executes the detach command
+func (c *DetachCmd) Run(cli *CLI) error {
+	if cli.Init != "" {
+		script, err := CompletionScript(cli.Init)
 		if err != nil {
 			return err
 		}
@@ -32,34 +174,186 @@ func (c *CLI) Run() error {
 		return nil
 	}
 
-	if c.Branch == "" {
-		return fmt.Errorf("branch name is required")
+	d, err := newDetacher()
+	if err != nil {
+		return err
 	}
 
-	d := NewDetacher()
-	d.LoadSuffixFromConfig()
+	if c.Expires, err = c.deadline(time.Now()); err != nil {
+		return err
+	}
 
-	opts := &Options{
-		DryRun: c.DryRun,
-		Revert: c.Revert,
-		Force:  c.Force,
-		Yes:    c.Yes,
+	if len(c.Branches) == 1 && c.Branches[0] == "-" {
+		c.Stdin = true
+		c.Branches = nil
+	}
+	if c.Stdin || len(c.Branches) > 1 || (len(c.Branches) == 1 && IsBranchPattern(c.Branches[0])) {
+		// A configured --checkout is for detaching a single branch
+		if _, ok := cli.configured["checkout"]; ok {
+			c.Checkout = false
+		}
+	}
+	if c.Stdin {
+		if len(c.Branches) > 0 || c.Checkout || c.Give != "" || c.To != "" || c.Worktree != "" || c.TempBranch != "" {
+			return fmt.Errorf("branches read from stdin cannot be combined with branch arguments, --checkout, --give, --to, --worktree, or --temp-branch")
+		}
+		return c.runStdin(d, c.options(cli, d))
+	}
+
+	if len(c.Branches) > 1 || (len(c.Branches) == 1 && IsBranchPattern(c.Branches[0])) {
+		if c.Revert || c.Checkout || c.Give != "" || c.To != "" || c.Worktree != "" {
+			return fmt.Errorf("multiple branches can only be detached, not combined with --revert, --checkout, --give, --to, or --worktree")
+		}
+		branches, err := d.ExpandBranches(c.Branches)
+		if err != nil {
+			return err
+		}
+		if len(branches) > 1 {
+			return c.runDetachAll(d, branches, c.options(cli, d))
+		}
+		c.Branches = branches
+	}
+	if len(c.Branches) == 1 {
+		c.Branch = c.Branches[0]
+	}
+
+	if c.Worktree != "" {
+		wt, err := d.ResolveBranchWorktree(c.Worktree)
+		if err != nil {
+			return err
+		}
+		switch {
+		case c.Revert && c.TempBranch == "":
+			tmpBranch, origin, err := d.TempBranchOf(wt)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("✔ Worktree %s is on temp branch: %s (original: %s)\n", wt.Path, tmpBranch, origin)
+			if c.Branch == "" {
+				c.Branch = origin
+			}
+			c.TempBranch = tmpBranch
+		case !c.Revert && c.Branch == "":
+			c.Branch = wt.Branch
+		}
+	}
+
+	if c.Branch == "" && c.Revert && c.TempBranch == "" {
+		tmpBranch, origin, err := d.CurrentTempBranch()
+		switch {
+		case err == nil:
+			fmt.Printf("✔ Current worktree is on temp branch: %s (original: %s)\n", tmpBranch, origin)
+			c.Branch = origin
+			c.TempBranch = tmpBranch
+		case !interactive():
+			return fmt.Errorf("branch name is required: %w", err)
+		}
+	}
+
+	if c.Branch == "" && c.Give != "" && !c.Revert {
+		currentPath, err := d.GetCurrentWorktreePath()
+		if err != nil {
+			return err
+		}
+		if c.Branch, _, err = d.CurrentHead(currentPath); err != nil {
+			return err
+		}
+		if c.Branch == "" {
+			return fmt.Errorf("current worktree is not on a branch (HEAD is detached)")
+		}
+	}
+
+	if c.Branch == "" && c.TempBranch == "" && interactive() {
+		if err := c.pickBranch(d); err != nil {
+			return err
+		}
+	}
+
+	if c.Branch == "" {
+		return fmt.Errorf("branch name is required")
 	}
 
+	opts := c.options(cli, d)
 	if c.Revert {
 		return c.runRevert(d, opts)
 	}
 	return c.runDetach(d, opts)
 }
 
-func (c *CLI) runDetach(d *Detacher, opts *Options) error {
+// pickBranch asks the user for the branch to detach or revert
+func (c *DetachCmd) pickBranch(d *Detacher) error {
+	title := "Branches checked out in other worktrees:"
+	candidates, err := d.DetachCandidates()
+	if c.Revert {
+		title = "Detached branches:"
+		candidates, err = d.RevertCandidates()
+	}
+	if err != nil {
+		return err
+	}
+	if len(candidates) == 0 {
+		if c.Revert {
+			return fmt.Errorf("branch name is required: no branch is detached")
+		}
+		return fmt.Errorf("branch name is required: no branch is checked out in another worktree")
+	}
+
+	picked := pickCandidate(title, candidates)
+	if picked == nil {
+		fmt.Println("Aborted.")
+		return errAborted
+	}
+	c.Branch = picked.Branch
+	c.TempBranch = picked.TempBranch
+	return nil
+}
+
+// options returns the Options for the command line
+func (c *DetachCmd) options(cli *CLI, d *Detacher) *Options {
+	return &Options{
+		DryRun:     cli.DryRun,
+		Revert:     c.Revert,
+		Force:      cli.Force,
+		Yes:        cli.Yes,
+		Track:      c.Track,
+		Checkout:   c.Checkout,
+		Give:       c.Give,
+		To:         c.To,
+		Worktree:   c.Worktree,
+		KeepGoing:  c.KeepGoing,
+		Expires:    c.Expires,
+		TempBranch: c.TempBranch,
+	}
+}
+
+// deadline returns the expiry given by --for or --until, zero if none
+func (c *DetachCmd) deadline(now time.Time) (time.Time, error) {
+	if c.For != "" || c.Until != "" {
+		if c.Revert {
+			return time.Time{}, fmt.Errorf("--for and --until cannot be used with --revert")
+		}
+	}
+	switch {
+	case c.For != "":
+		dur, err := ParseExpiry(c.For)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return now.Add(dur), nil
+	case c.Until != "":
+		return ParseDeadline(c.Until, now)
+	}
+	return time.Time{}, nil
+}
+
+func (c *DetachCmd) runDetach(d *Detacher, opts *Options) (err error) {
 	branch := c.Branch
 
 	if !d.BranchExists(branch) {
 		return fmt.Errorf("branch '%s' does not exist", branch)
 	}
 
-	wt, err := d.FindWorktreeForBranch(branch)
+	wt, err := d.FindDetachWorktree(branch, opts)
 	if err != nil {
 		return err
 	}
@@ -69,70 +363,286 @@ func (c *CLI) runDetach(d *Detacher, opts *Options) error {
 		return nil
 	}
 
-	fmt.Printf("✔ Found worktree: %s\n", wt.Path)
+	fmt.Printf("✔ Found worktree: %s%s\n", wt.Path, lockNote(wt))
 
-	if d.HasUncommittedChanges(wt.Path) {
-		if !opts.Force {
-			return formatUncommittedError(wt.Path, d.GetUncommittedFiles(wt.Path))
-		}
-		fmt.Printf("⚠ Warning: Uncommitted changes found in worktree: %s\n", wt.Path)
+	checkoutPath, err := d.FindCheckoutWorktree(wt, opts)
+	if err != nil {
+		return err
+	}
+	if opts.Give != "" {
+		fmt.Printf("✔ Giving to worktree: %s\n", checkoutPath)
+	}
+	if opts.To != "" {
+		fmt.Printf("✔ Target worktree: %s\n", checkoutPath)
 	}
 
-	tmpBranch := d.TempBranchName(branch)
+	tmpBranch, err := d.TempBranchNameFor(branch, wt)
+	if err != nil {
+		return err
+	}
+	if err := d.checkTempBranchFree(tmpBranch); err != nil {
+		return err
+	}
+
+	dirtyPaths := []string{wt.Path}
+	if opts.Give != "" || opts.To != "" {
+		dirtyPaths = append(dirtyPaths, checkoutPath)
+	}
+	dirty := newDirtyResolver(d, &opts.Force, opts.DryRun, opts.Yes, "detach "+branch)
+	defer dirty.finish(&err)
+	for _, path := range dirtyPaths {
+		if err := dirty.check(path); err != nil {
+			return err
+		}
+	}
 
 	if opts.DryRun {
 		fmt.Printf("would create branch: %s\n", tmpBranch)
+		if opts.Track {
+			fmt.Printf("would copy upstream tracking config to: %s\n", tmpBranch)
+		}
 		fmt.Printf("would checkout in worktree: %s\n", wt.Path)
-		if c.Checkout {
-			fmt.Printf("would checkout branch: %s\n", branch)
+		if !wt.Main && !wt.Locked {
+			fmt.Printf("would lock worktree: %s\n", wt.Path)
+		}
+		if !opts.Expires.IsZero() {
+			fmt.Printf("would expire at: %s\n", formatTime(opts.Expires))
+		}
+		if checkoutPath != "" {
+			fmt.Printf("would checkout branch %s in worktree: %s\n", branch, checkoutPath)
 		}
 		return nil
 	}
 
-	if !opts.Yes {
-		if !c.confirm(branch, wt.Path, tmpBranch) {
-			fmt.Println("Aborted.")
-			return nil
+	ask, err := shouldConfirm(d, opts.Yes, dirty.found)
+	if err != nil {
+		return err
+	}
+	if ask {
+		if err := c.confirm(branch, wt.Path, tmpBranch, opts.Give != "" || opts.To != "", checkoutPath); err != nil {
+			return err
 		}
 	}
 
 	result, err := d.Detach(branch, opts)
-	if err != nil {
+	if result == nil {
 		return err
 	}
 
 	fmt.Printf("✔ Created temp branch: %s\n", result.TempBranch)
+	if result.Tracking {
+		fmt.Printf("✔ Copied upstream tracking config (push disabled)\n")
+	}
 	fmt.Printf("✔ Switched worktree branch\n")
+	if result.Snapshot != "" {
+		fmt.Printf("✔ Saved a copy of the uncommitted changes at: %s\n", result.Snapshot)
+	}
+	if result.Locked {
+		fmt.Printf("✔ Locked worktree: %s\n", result.WorktreePath)
+	}
 	fmt.Printf("✔ Branch detached: %s\n", branch)
+	if !opts.Expires.IsZero() {
+		fmt.Printf("✔ Expires: %s (revert with: git wt-detach expire)\n", formatTime(opts.Expires))
+	}
+	if err != nil {
+		return fmt.Errorf("%w\n  Branch '%s' stays detached. Check it out by hand, or revert with: git wt-detach %s --revert", err, branch, branch)
+	}
+	if result.CheckoutPath != "" {
+		fmt.Printf("✔ Checked out: %s in %s\n", branch, result.CheckoutPath)
+	}
+	if result.HookErr != nil {
+		fmt.Printf("⚠ Warning: %s\n", result.HookErr)
+	}
 
-	if c.Checkout {
-		currentPath, err := d.GetCurrentWorktreePath()
+	return nil
+}
+
+// plannedDetach is a branch of a batch that will be detached
+type plannedDetach struct {
+	branch    string
+	tmpBranch string
+	wt        *Worktree
+}
+
+func (c *DetachCmd) runDetachAll(d *Detacher, branches []string, opts *Options) (err error) {
+	dirty := newDirtyResolver(d, &opts.Force, opts.DryRun, opts.Yes, "detach "+strings.Join(branches, ", "))
+	defer dirty.finish(&err)
+
+	var plans []plannedDetach
+	var skipped int
+	for _, branch := range branches {
+		p, err := c.planDetach(d, branch, dirty)
 		if err != nil {
+			if !opts.KeepGoing || errors.Is(err, errAborted) {
+				return err
+			}
+			fmt.Printf("⚠ Skipping %s: %v\n", branch, err)
+			skipped++
+			continue
+		}
+		if p != nil {
+			plans = append(plans, *p)
+		}
+	}
+
+	if len(plans) == 0 {
+		if skipped > 0 {
+			return fmt.Errorf("%d branch(es) could not be detached", skipped)
+		}
+		return nil
+	}
+
+	if opts.DryRun {
+		for _, p := range plans {
+			fmt.Printf("would create branch: %s\n", p.tmpBranch)
+			if opts.Track {
+				fmt.Printf("would copy upstream tracking config to: %s\n", p.tmpBranch)
+			}
+			fmt.Printf("would checkout in worktree: %s\n", p.wt.Path)
+			if !p.wt.Main && !p.wt.Locked {
+				fmt.Printf("would lock worktree: %s\n", p.wt.Path)
+			}
+		}
+		return nil
+	}
+
+	ask, err := shouldConfirm(d, opts.Yes, dirty.found)
+	if err != nil {
+		return err
+	}
+	if ask {
+		fmt.Printf("The following branches will be temporarily replaced:\n")
+		for _, p := range plans {
+			fmt.Printf("  %s -> %s\n", p.branch, p.tmpBranch)
+			fmt.Printf("      in %s\n", p.wt.Path)
+		}
+		fmt.Println()
+		if err := proceed(); err != nil {
 			return err
 		}
-		if err := d.Checkout(currentPath, branch); err != nil {
+	}
+
+	names := make([]string, len(plans))
+	for i, p := range plans {
+		names[i] = p.branch
+	}
+	results, err := d.DetachAll(names, opts)
+	var failed int
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			fmt.Printf("✖ Failed to detach %s: %v\n", r.Branch, r.Err)
+			failed++
+		case r.RolledBack:
+			fmt.Printf("↩ Rolled back: %s\n", r.Branch)
+		default:
+			fmt.Printf("✔ Branch detached: %s -> %s%s\n", r.Branch, r.Result.TempBranch, describeLock(r.Result))
+			if r.Result.HookErr != nil {
+				fmt.Printf("⚠ Warning: %s\n", r.Result.HookErr)
+			}
+		}
+	}
+
+	if err != nil && !opts.KeepGoing {
+		return fmt.Errorf("batch rolled back: %w", err)
+	}
+	if failed+skipped > 0 {
+		return fmt.Errorf("%d of %d branch(es) could not be detached", failed+skipped, len(branches))
+	}
+	return nil
+}
+
+// planDetach checks that a branch of a batch can be detached, letting dirty
+// deal with uncommitted changes in its worktree. It returns nil if the branch
+// is not checked out in another worktree.
+func (c *DetachCmd) planDetach(d *Detacher, branch string, dirty *dirtyResolver) (*plannedDetach, error) {
+	if !d.BranchExists(branch) {
+		return nil, fmt.Errorf("branch '%s' does not exist", branch)
+	}
+
+	wt, err := d.FindWorktreeForBranch(branch)
+	if err != nil {
+		return nil, err
+	}
+	if wt == nil {
+		fmt.Printf("Branch '%s' is not checked out in any other worktree.\n", branch)
+		return nil, nil
+	}
+	fmt.Printf("✔ Found worktree for %s: %s%s\n", branch, wt.Path, lockNote(wt))
+
+	tmpBranch, err := d.TempBranchNameFor(branch, wt)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.checkTempBranchFree(tmpBranch); err != nil {
+		return nil, err
+	}
+	if err := dirty.check(wt.Path); err != nil {
+		return nil, err
+	}
+
+	return &plannedDetach{branch: branch, tmpBranch: tmpBranch, wt: wt}, nil
+}
+
+// runStdin detaches or reverts each branch listed on stdin and prints a JSON
+// line per entry. Stdin holds the branches and cannot answer a prompt, so
+// this needs --yes unless wt-detach.confirm says not to ask.
+func (c *DetachCmd) runStdin(d *Detacher, opts *Options) error {
+	// Uncommitted changes are only carried along with --force
+	ask, err := shouldConfirm(d, opts.Yes || opts.DryRun, opts.Force)
+	if err != nil {
+		return err
+	}
+	if ask {
+		return fmt.Errorf("confirmation required, but stdin holds the branches\n  Use --yes to proceed without confirmation")
+	}
+
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return fmt.Errorf("failed to read branches from stdin: %w", err)
+	}
+	entries := ParseBranchList(data)
+
+	enc := json.NewEncoder(os.Stdout)
+	var failed int
+	for _, entry := range entries {
+		result := d.ApplyEntry(entry, *opts)
+		if !result.OK {
+			failed++
+		}
+		if err := enc.Encode(result); err != nil {
 			return err
 		}
-		fmt.Printf("✔ Checked out: %s\n", branch)
 	}
 
+	if failed > 0 {
+		return fmt.Errorf("%d of %d entries failed", failed, len(entries))
+	}
 	return nil
 }
 
-func (c *CLI) runRevert(d *Detacher, opts *Options) error {
+func (c *DetachCmd) runRevert(d *Detacher, opts *Options) (err error) {
 	branch := c.Branch
-	tmpBranch := d.TempBranchName(branch)
-
-	if !d.BranchExists(tmpBranch) {
-		return fmt.Errorf("temporary branch '%s' does not exist", tmpBranch)
+	tmpBranch, err := d.resolveTempBranch(branch, opts.TempBranch)
+	if err != nil {
+		return err
 	}
 
-	wt, err := d.FindWorktreeForBranch(tmpBranch)
+	wt, err := d.FindWorktreeForTempBranch(tmpBranch)
 	if err != nil {
 		return err
 	}
 
+	st := d.LoadState(tmpBranch)
+	if now := time.Now(); !st.Expires.IsZero() && st.Expires.Before(now) {
+		fmt.Printf("⚠ Warning: Detach expired %s ago, on %s\n", formatDuration(now.Sub(st.Expires)), formatTime(st.Expires))
+	}
+
 	if wt == nil {
+		if status, n := d.ClassifyTempBranch(tmpBranch, branch); status == TempExtraCommits {
+			fmt.Printf("⚠ Warning: Temp branch '%s' has %d commit(s) not in '%s'\n", tmpBranch, n, branch)
+		}
+
 		if opts.DryRun {
 			fmt.Printf("would delete branch: %s\n", tmpBranch)
 			return nil
@@ -142,32 +652,77 @@ func (c *CLI) runRevert(d *Detacher, opts *Options) error {
 		if err != nil {
 			return err
 		}
+		if result.Unlocked {
+			fmt.Printf("✔ Unlocked worktree\n")
+		}
 		fmt.Printf("✔ Deleted temp branch: %s\n", result.TempBranch)
+		if result.HookErr != nil {
+			fmt.Printf("⚠ Warning: %s\n", result.HookErr)
+		}
 		return nil
 	}
 
-	fmt.Printf("✔ Found worktree with temp branch: %s\n", wt.Path)
+	fmt.Printf("✔ Found worktree with temp branch: %s%s\n", wt.Path, lockNote(wt))
+
+	holder, err := d.FindBranchHolder(branch, st, wt.Path)
+	if err != nil {
+		return err
+	}
 
-	if d.HasUncommittedChanges(wt.Path) {
-		if !opts.Force {
-			return formatUncommittedError(wt.Path, d.GetUncommittedFiles(wt.Path))
+	// The report comes before the check for uncommitted changes, since it
+	// shows the files that would block the checkout
+	var dirtyPaths []string
+	for _, path := range worktreesToCheck(wt, holder) {
+		if d.HasUncommittedChanges(path) {
+			dirtyPaths = append(dirtyPaths, path)
 		}
-		fmt.Printf("⚠ Warning: Uncommitted changes found in worktree: %s\n", wt.Path)
+	}
+	ask, askErr := shouldConfirm(d, opts.Yes || opts.DryRun, len(dirtyPaths) > 0)
+	if opts.DryRun || ask {
+		diff, err := d.DiffForRevert(tmpBranch, branch, wt.Path)
+		if err != nil {
+			return err
+		}
+		printRevertDiff(diff, branch, tmpBranch, wt.Path)
+	} else if status, n := d.ClassifyTempBranch(tmpBranch, branch); status == TempExtraCommits {
+		// Without the report, commits only on the temp branch are still worth a warning
+		fmt.Printf("⚠ Warning: Temp branch '%s' has %d commit(s) not in '%s', which will be deleted with it\n", tmpBranch, n, branch)
 	}
 
 	if opts.DryRun {
+		for _, path := range dirtyPaths {
+			fmt.Printf("⚠ Warning: Uncommitted changes found in worktree: %s\n", path)
+			if !opts.Force {
+				fmt.Printf("  Use --force to override\n")
+			}
+		}
+		if holder != nil {
+			fmt.Printf("would checkout in worktree: %s -> %s\n", holder.Path, describePrevious(st))
+		}
 		fmt.Printf("would checkout branch in worktree: %s -> %s\n", wt.Path, branch)
 		fmt.Printf("would delete branch: %s\n", tmpBranch)
 		return nil
 	}
 
-	if !opts.Yes {
+	dirty := newDirtyResolver(d, &opts.Force, opts.DryRun, opts.Yes, "revert "+branch)
+	defer dirty.finish(&err)
+	for _, path := range dirtyPaths {
+		if err := dirty.check(path); err != nil {
+			return err
+		}
+	}
+	if askErr != nil {
+		return askErr
+	}
+
+	if ask {
+		if holder != nil {
+			fmt.Printf("Worktree '%s' will be switched back to %s\n", holder.Path, describePrevious(st))
+		}
 		fmt.Printf("Worktree '%s' will be switched back to branch '%s'\n", wt.Path, branch)
 		fmt.Printf("Temporary branch '%s' will be deleted.\n\n", tmpBranch)
-		fmt.Print("Proceed? [y/N] ")
-		if !readYesNo() {
-			fmt.Println("Aborted.")
-			return nil
+		if err := proceed(); err != nil {
+			return err
 		}
 	}
 
@@ -176,28 +731,612 @@ func (c *CLI) runRevert(d *Detacher, opts *Options) error {
 		return err
 	}
 
+	if result.ReleasedPath != "" {
+		fmt.Printf("✔ Switched worktree %s to: %s\n", result.ReleasedPath, result.ReleasedTo)
+	}
 	fmt.Printf("✔ Switched worktree to: %s\n", branch)
+	if result.Unlocked {
+		fmt.Printf("✔ Unlocked worktree: %s\n", result.WorktreePath)
+	}
 	fmt.Printf("✔ Deleted temp branch: %s\n", result.TempBranch)
 	fmt.Printf("✔ Branch restored: %s\n", branch)
+	if result.HookErr != nil {
+		fmt.Printf("⚠ Warning: %s\n", result.HookErr)
+	}
+	if result.Conflict != nil {
+		return result.Conflict
+	}
 	return nil
 }
 
-func (c *CLI) confirm(branch, worktreePath, tmpBranch string) bool {
+// Run executes the gc command
+func (c *GCCmd) Run(cli *CLI) error {
+	d, err := newDetacher()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	orphans, err := d.GC(&Options{DryRun: cli.DryRun, Force: cli.Force})
+	for _, o := range orphans {
+		desc := fmt.Sprintf("%s (original: %s): %s, %s", o.TempBranch, o.Origin, o.Reason, describeStatus(o))
+		if !o.Expires.IsZero() && o.Expires.Before(now) {
+			desc += fmt.Sprintf(", expired %s ago", formatDuration(now.Sub(o.Expires)))
+		}
+		switch {
+		case o.Deleted:
+			fmt.Printf("✔ Deleted %s\n", desc)
+			if path := o.MissingWorktree(); path != "" {
+				fmt.Printf("✔ Removed missing worktree: %s\n", path)
+			}
+		case cli.DryRun && (o.Safe() || cli.Force):
+			fmt.Printf("would delete %s\n", desc)
+			if path := o.MissingWorktree(); path != "" {
+				fmt.Printf("would remove missing worktree: %s\n", path)
+			}
+		case cli.DryRun:
+			fmt.Printf("would keep %s\n", desc)
+		default:
+			fmt.Printf("⚠ Kept %s\n  Use --force to delete\n", desc)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := d.GCSnapshots(&Options{DryRun: cli.DryRun, Force: cli.Force})
+	for _, s := range snapshots {
+		desc := fmt.Sprintf("snapshot %s: temp branch '%s' no longer exists", s.Ref, s.TempBranch)
+		switch {
+		case s.Deleted:
+			fmt.Printf("✔ Deleted %s\n", desc)
+		case cli.DryRun && cli.Force:
+			fmt.Printf("would delete %s\n", desc)
+		case cli.DryRun:
+			fmt.Printf("would keep %s\n", desc)
+		default:
+			fmt.Printf("⚠ Kept %s\n  It may hold changes revert could not restore: git stash show -p %s\n  Use --force to delete\n", desc, s.Ref)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(orphans) == 0 && len(snapshots) == 0 {
+		fmt.Println("No orphaned temp branches found.")
+	}
+	return nil
+}
+
+// Run executes the doctor command
+func (c *DoctorCmd) Run(cli *CLI) error {
+	d, err := newDetacher()
+	if err != nil {
+		return err
+	}
+
+	problems, err := d.Diagnose(cli.Force)
+	if err != nil {
+		return err
+	}
+	if len(problems) == 0 {
+		fmt.Println("✔ No problems found.")
+		return nil
+	}
+
+	var repairErr error
+	if c.Fix && !cli.DryRun {
+		repairErr = d.Repair(problems)
+	}
+
+	remaining := 0
+	for _, p := range problems {
+		if !p.Fixed {
+			remaining++
+		}
+		fmt.Printf("✖ %s\n", p.Description)
+		switch {
+		case p.Fixed:
+			fmt.Printf("  ✔ Fixed: %s\n", p.Fix)
+		case p.Fix == "":
+			fmt.Printf("  Needs to be fixed by hand.\n")
+		case p.NeedsForce:
+			fmt.Printf("  Use --force with --fix to %s anyway.\n", p.Fix)
+		case c.Fix && cli.DryRun:
+			fmt.Printf("  would %s\n", p.Fix)
+		case c.Fix:
+			fmt.Printf("  ⚠ Could not %s\n", p.Fix)
+		default:
+			fmt.Printf("  Fix: %s (run with --fix)\n", p.Fix)
+		}
+	}
+	if repairErr != nil {
+		return repairErr
+	}
+	if remaining > 0 {
+		return fmt.Errorf("%d problem(s) remaining", remaining)
+	}
+	return nil
+}
+
+// Run executes the swap command
+func (c *SwapCmd) Run(cli *CLI) (err error) {
+	d, err := newDetacher()
+	if err != nil {
+		return err
+	}
+
+	plan, err := d.PlanSwap(c.Target)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✔ Found worktree: %s (%s)\n", plan.OtherPath, plan.OtherBranch)
+
+	dirty := newDirtyResolver(d, &cli.Force, cli.DryRun, cli.Yes, "swap "+plan.CurrentBranch+" and "+plan.OtherBranch)
+	defer dirty.finish(&err)
+	for _, path := range []string{plan.CurrentPath, plan.OtherPath} {
+		if err := dirty.check(path); err != nil {
+			return err
+		}
+	}
+
+	if cli.DryRun {
+		fmt.Printf("would checkout in worktree: %s -> %s\n", plan.CurrentPath, plan.OtherBranch)
+		fmt.Printf("would checkout in worktree: %s -> %s\n", plan.OtherPath, plan.CurrentBranch)
+		return nil
+	}
+
+	ask, err := shouldConfirm(d, cli.Yes, dirty.found)
+	if err != nil {
+		return err
+	}
+	if ask {
+		fmt.Printf("Worktree '%s' will be switched from '%s' to '%s'\n", plan.CurrentPath, plan.CurrentBranch, plan.OtherBranch)
+		fmt.Printf("Worktree '%s' will be switched from '%s' to '%s'\n\n", plan.OtherPath, plan.OtherBranch, plan.CurrentBranch)
+		if err := proceed(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := d.Swap(c.Target, &Options{Force: cli.Force}); err != nil {
+		return err
+	}
+
+	fmt.Printf("✔ Switched worktree %s to: %s\n", plan.CurrentPath, plan.OtherBranch)
+	fmt.Printf("✔ Switched worktree %s to: %s\n", plan.OtherPath, plan.CurrentBranch)
+	return nil
+}
+
+// Run executes the move command
+func (c *MoveCmd) Run(cli *CLI) (err error) {
+	d, err := newDetacher()
+	if err != nil {
+		return err
+	}
+
+	if !d.BranchExists(c.Branch) {
+		return fmt.Errorf("branch '%s' does not exist", c.Branch)
+	}
+
+	wt, err := d.FindWorktreeForBranch(c.Branch)
+	if err != nil {
+		return err
+	}
+	dirty := newDirtyResolver(d, &cli.Force, cli.DryRun, cli.Yes, "move "+c.Branch)
+	defer dirty.finish(&err)
+	if wt == nil {
+		fmt.Printf("Branch '%s' is not checked out in any other worktree.\n", c.Branch)
+	} else {
+		fmt.Printf("✔ Found worktree: %s%s\n", wt.Path, lockNote(wt))
+		if err := dirty.check(wt.Path); err != nil {
+			return err
+		}
+	}
+
+	opts := &Options{DryRun: true, Force: cli.Force}
+	plan, err := d.Move(c.Branch, c.Fallback, opts)
+	if err != nil {
+		return err
+	}
+
+	fallbackDesc := "detached HEAD"
+	if plan.Fallback != "" {
+		fallbackDesc = fmt.Sprintf("branch '%s'", plan.Fallback)
+	}
+
+	if cli.DryRun {
+		if plan.WorktreePath != "" {
+			fmt.Printf("would checkout in worktree: %s -> %s\n", plan.WorktreePath, fallbackDesc)
+		}
+		fmt.Printf("would checkout branch: %s\n", c.Branch)
+		return nil
+	}
+
+	ask, err := shouldConfirm(d, cli.Yes || plan.WorktreePath == "", dirty.found)
+	if err != nil {
+		return err
+	}
+	if ask {
+		fmt.Printf("Branch '%s' will be moved from:\n", c.Branch)
+		fmt.Printf("  %s\n\n", plan.WorktreePath)
+		fmt.Printf("That worktree will be left on:\n")
+		fmt.Printf("  %s\n\n", fallbackDesc)
+		if err := proceed(); err != nil {
+			return err
+		}
+	}
+
+	opts.DryRun = false
+	result, err := d.Move(c.Branch, c.Fallback, opts)
+	if err != nil {
+		return err
+	}
+
+	if result.WorktreePath != "" {
+		fmt.Printf("✔ Switched worktree %s to: %s\n", result.WorktreePath, fallbackDesc)
+	}
+	fmt.Printf("✔ Checked out: %s\n", c.Branch)
+	return nil
+}
+
+// Run executes the exec command
+func (c *ExecCmd) Run(cli *CLI) (err error) {
+	args := c.Command
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("command is required: git wt-detach exec <branch> -- <command...>")
+	}
+
+	d, err := newDetacher()
+	if err != nil {
+		return err
+	}
+
+	if !d.BranchExists(c.Branch) {
+		return fmt.Errorf("branch '%s' does not exist", c.Branch)
+	}
+
+	wt, err := d.FindWorktreeForBranch(c.Branch)
+	if err != nil {
+		return err
+	}
+	dirty := newDirtyResolver(d, &cli.Force, cli.DryRun, cli.Yes, "borrow "+c.Branch)
+	defer dirty.finish(&err)
+	if wt != nil {
+		fmt.Printf("✔ Found worktree: %s%s\n", wt.Path, lockNote(wt))
+		if err := dirty.check(wt.Path); err != nil {
+			return err
+		}
+	}
+
+	if cli.DryRun {
+		if wt != nil {
+			fmt.Printf("would detach branch from worktree: %s\n", wt.Path)
+		}
+		fmt.Printf("would checkout branch: %s\n", c.Branch)
+		fmt.Printf("would run: %s\n", strings.Join(args, " "))
+		fmt.Printf("would revert both worktrees\n")
+		return nil
+	}
+
+	ask, err := shouldConfirm(d, cli.Yes || wt == nil, dirty.found)
+	if err != nil {
+		return err
+	}
+	if ask {
+		fmt.Printf("Branch '%s' will be borrowed from:\n", c.Branch)
+		fmt.Printf("  %s\n\n", wt.Path)
+		fmt.Printf("to run:\n")
+		fmt.Printf("  %s\n\n", strings.Join(args, " "))
+		if err := proceed(); err != nil {
+			return err
+		}
+	}
+
+	opts := &Options{
+		Force: cli.Force,
+		Yes:   true,
+		Track: d.ConfigBool("track"),
+	}
+	result, err := d.Exec(c.Branch, args, opts)
+	if err != nil {
+		return err
+	}
+
+	code := result.Code
+	if result.RunErr != nil {
+		fmt.Fprintf(os.Stderr, "✖ failed to run command: %s\n", result.RunErr)
+	}
+	if result.ReturnErr != nil {
+		fmt.Fprintf(os.Stderr, "✖ %s\n", result.ReturnErr)
+		if code == 0 {
+			code = 1
+		}
+	} else if result.TempBranch != "" || result.Switched {
+		fmt.Printf("✔ Reverted: %s\n", c.Branch)
+	}
+
+	if code != 0 {
+		return &ExitError{Code: code}
+	}
+	return nil
+}
+
+// Run executes the expire command
+func (c *ExpireCmd) Run(cli *CLI) error {
+	d, err := newDetacher()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	expired, err := d.FindExpiredDetaches(now)
+	if err != nil {
+		return err
+	}
+	// Silent when there is nothing to do, since it runs from cron or a hook
+	if len(expired) == 0 {
+		return nil
+	}
+
+	opts := &Options{DryRun: cli.DryRun, Force: cli.Force}
+	if !cli.DryRun {
+		err = d.ExpireDetaches(expired, opts)
+	}
+
+	for _, e := range expired {
+		desc := fmt.Sprintf("%s (temp branch: %s), expired %s ago", e.Origin, e.TempBranch, formatDuration(e.Overdue(now)))
+		switch {
+		case e.Reverted:
+			fmt.Printf("✔ Reverted %s\n", desc)
+		case e.Err != nil:
+			fmt.Printf("✖ Failed to revert %s: %v\n", desc, e.Err)
+		case e.Worktree == nil:
+			fmt.Printf("⚠ Kept %s\n  It is not checked out in any worktree; see git wt-detach gc\n", desc)
+		case len(e.DirtyWorktrees) > 0 && !cli.Force:
+			fmt.Printf("⚠ Kept %s\n  Uncommitted changes in: %s\n  Use --force to revert anyway\n", desc, strings.Join(e.DirtyWorktrees, ", "))
+		default:
+			fmt.Printf("would revert %s\n", desc)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("some expired detaches could not be reverted")
+	}
+	return nil
+}
+
+// Run executes the refresh command
+func (c *RefreshCmd) Run(cli *CLI) error {
+	d, err := newDetacher()
+	if err != nil {
+		return err
+	}
+	opts := &Options{DryRun: cli.DryRun, Force: cli.Force}
+
+	if c.Branch != "" {
+		result, err := d.Refresh(c.Branch, opts)
+		if err != nil {
+			return err
+		}
+		printRefresh(result, cli.DryRun)
+		return nil
+	}
+
+	states, err := d.ListTempBranches()
+	if err != nil {
+		return err
+	}
+	if len(states) == 0 {
+		fmt.Println("No detached branches found.")
+		return nil
+	}
+
+	var failed int
+	for _, st := range states {
+		result, err := d.Refresh(st.Origin, &Options{DryRun: opts.DryRun, Force: opts.Force, TempBranch: st.TempBranch})
+		if err != nil {
+			fmt.Printf("⚠ Skipped %s: %v\n", st.TempBranch, err)
+			failed++
+			continue
+		}
+		printRefresh(result, cli.DryRun)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d temp branch(es) could not be refreshed", failed)
+	}
+	return nil
+}
+
+// Run executes the config command
+func (c *ConfigCmd) Run(ctx *kong.Context) error {
+	d := NewDetacher()
+
+	type row struct{ key, flag, def string }
+	var rows []row
+	var walk func(node *kong.Node)
+	walk = func(node *kong.Node) {
+		for _, flag := range node.Flags {
+			key := flag.Tag.Get("config")
+			if key == "" || slices.ContainsFunc(rows, func(r row) bool { return r.key == key }) {
+				continue
+			}
+			def := flag.Default
+			if def == "" && flag.IsBool() {
+				def = "false"
+			}
+			rows = append(rows, row{key: key, flag: "--" + flag.Name, def: def})
+		}
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	walk(ctx.Model.Node)
+	for _, s := range Settings {
+		rows = append(rows, row{key: s.Key, def: s.Default})
+	}
+	slices.SortFunc(rows, func(a, b row) int { return strings.Compare(a.key, b.key) })
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE\tSOURCE\tFLAG")
+	for _, r := range rows {
+		value, source, ok := d.LookupConfig(r.key)
+		if !ok {
+			value, source = r.def, "default"
+		}
+		if value == "" {
+			value = "-"
+		}
+		fmt.Fprintf(w, "wt-detach.%s\t%s\t%s\t%s\n", r.key, value, source, r.flag)
+	}
+	w.Flush()
+
+	fmt.Println()
+	fmt.Println("Set with:      git config [--global|--system] wt-detach.<key> <value>")
+	fmt.Println("Override with: GIT_WT_DETACH_<KEY>=<value>, like GIT_WT_DETACH_KEEP_GOING=true")
+	return nil
+}
+
+// printRefresh prints the result of refreshing a temp branch
+func printRefresh(result *RefreshResult, dryRun bool) {
+	if result.UpToDate {
+		fmt.Printf("✔ %s is up to date with %s\n", result.TempBranch, result.Origin)
+		return
+	}
+	change := fmt.Sprintf("%s: %s..%s", result.TempBranch, shortHash(result.From), shortHash(result.To))
+	if result.WorktreePath != "" {
+		change += fmt.Sprintf(" (worktree: %s)", result.WorktreePath)
+	}
+	if dryRun {
+		fmt.Printf("would fast-forward %s\n", change)
+		return
+	}
+	fmt.Printf("✔ Fast-forwarded %s\n", change)
+}
+
+// shortHash abbreviates a commit hash for display
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
+
+// formatTime formats a deadline for display
+func formatTime(t time.Time) string {
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// maxReportLines is how many commits or files a report lists before
+// summarizing the rest
+const maxReportLines = 10
+
+// printRevertDiff reports how a worktree changes when it is switched back
+// from the temp branch to the original branch
+func printRevertDiff(diff *RevertDiff, branch, tmpBranch, worktreePath string) {
+	if diff.Empty() {
+		return
+	}
+
+	if len(diff.Commits) > 0 {
+		fmt.Printf("Branch '%s' has %d new commit(s) since it was detached:\n", branch, len(diff.Commits))
+		printLimited(diff.Commits)
+	}
+	if diff.TempOnly > 0 {
+		fmt.Printf("⚠ Warning: Temp branch '%s' has %d commit(s) not in '%s', which will be deleted with it\n", tmpBranch, diff.TempOnly, branch)
+	}
+	if len(diff.Files) > 0 {
+		fmt.Printf("Files that will change in %s (%s):\n", worktreePath, diff.Stat)
+		files := make([]string, len(diff.Files))
+		for i, f := range diff.Files {
+			files[i] = fmt.Sprintf("%s %s", f.Status, f.Path)
+		}
+		printLimited(files)
+	}
+	if len(diff.Blocking) > 0 {
+		fmt.Printf("⚠ Warning: Untracked files in %s would block the checkout:\n", worktreePath)
+		printLimited(diff.Blocking)
+	}
+	if len(diff.Overwritten) > 0 {
+		fmt.Printf("⚠ Warning: Ignored files in %s would be overwritten:\n", worktreePath)
+		printLimited(diff.Overwritten)
+	}
+	fmt.Println()
+}
+
+// printLimited prints indented lines, summarizing those past maxReportLines
+func printLimited(lines []string) {
+	for i, line := range lines {
+		if i == maxReportLines {
+			fmt.Printf("  ... and %d more\n", len(lines)-maxReportLines)
+			return
+		}
+		fmt.Printf("  %s\n", line)
+	}
+}
+
+// describeStatus describes the classification of an orphaned temp branch
+func describeStatus(o *OrphanedTempBranch) string {
+	if o.Status == TempExtraCommits && o.ExtraCommits > 0 {
+		return fmt.Sprintf("has %d extra commit(s)", o.ExtraCommits)
+	}
+	return o.Status
+}
+
+func (c *DetachCmd) confirm(branch, worktreePath, tmpBranch string, elsewhere bool, checkoutPath string) error {
 	fmt.Printf("Branch '%s' is currently checked out in:\n", branch)
 	fmt.Printf("  %s\n\n", worktreePath)
 	fmt.Printf("It will be temporarily replaced by:\n")
 	fmt.Printf("  %s\n\n", tmpBranch)
-	fmt.Print("Proceed? [y/N] ")
-	return readYesNo()
+	if elsewhere {
+		fmt.Printf("and checked out in:\n")
+		fmt.Printf("  %s\n\n", checkoutPath)
+	}
+	return proceed()
 }
 
-func readYesNo() bool {
-	reader := bufio.NewReader(os.Stdin)
-	input, err := reader.ReadString('\n')
-	if err != nil {
-		return false
+// worktreesToCheck returns the paths of the worktrees a revert switches
+func worktreesToCheck(wt, holder *Worktree) []string {
+	paths := []string{wt.Path}
+	if holder != nil {
+		paths = append(paths, holder.Path)
+	}
+	return paths
+}
+
+// describePrevious describes what a worktree is switched back to on revert
+func describePrevious(st *State) string {
+	if st.PrevBranch != "" {
+		return fmt.Sprintf("branch '%s'", st.PrevBranch)
+	}
+	if len(st.PrevHead) >= 7 {
+		return fmt.Sprintf("detached HEAD at %s", st.PrevHead[:7])
+	}
+	return "detached HEAD"
+}
+
+// describeLock returns a note on the worktree locked by a detach, if any
+func describeLock(result *Result) string {
+	if !result.Locked {
+		return ""
 	}
-	input = strings.TrimSpace(strings.ToLower(input))
+	return fmt.Sprintf(" (locked %s)", result.WorktreePath)
+}
+
+// lockNote returns a note describing the lock state of a worktree
+func lockNote(wt *Worktree) string {
+	if !wt.Locked {
+		return ""
+	}
+	if wt.LockReason == "" {
+		return " (locked)"
+	}
+	return fmt.Sprintf(" (locked: %s)", wt.LockReason)
+}
+
+func readYesNo() bool {
+	input, _ := readLine()
 	return input == "y" || input == "yes"
 }
 
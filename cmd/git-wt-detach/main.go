@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -18,9 +19,14 @@ func main() {
 		kong.Description("Temporarily detach a branch checked out in another worktree."),
 		kong.UsageOnError(),
 		kong.Vars{"version": version},
+		kong.Resolvers(cli.ConfigResolver()),
 	)
 
-	if err := cli.Run(); err != nil {
+	if err := ctx.Run(&cli); err != nil {
+		var exitErr *wtdetach.ExitError
+		if errors.As(err, &exitErr) {
+			ctx.Exit(exitErr.Code)
+		}
 		fmt.Fprintf(os.Stderr, "✖ %s\n", err)
 		ctx.Exit(1)
 	}
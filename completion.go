@@ -21,10 +21,30 @@ _git_wt_detach_branches() {
     git worktree list --porcelain 2>/dev/null | grep '^branch ' | sed 's/^branch refs\/heads\///'
 }
 
+_git_wt_detach_commands="detach gc doctor swap move exec expire refresh config"
+
+# Completes the arguments starting at COMP_WORDS[$1]: commands only in the
+# first position, and branches unless the command takes none
+_git_wt_detach_complete() {
+    local cur="${COMP_WORDS[COMP_CWORD]}" cmd="" i
+    for ((i = $1; i < COMP_CWORD; i++)); do
+        if [[ ${COMP_WORDS[i]} != -* ]]; then
+            cmd=${COMP_WORDS[i]}
+            break
+        fi
+    done
+
+    local words
+    case "${cmd}" in
+        "") words="$(_git_wt_detach_branches) ${_git_wt_detach_commands}" ;;
+        gc|doctor|expire|config) words="" ;;
+        *) words="$(_git_wt_detach_branches)" ;;
+    esac
+    COMPREPLY=($(compgen -W "${words}" -- "${cur}"))
+}
+
 _git_wt_detach() {
-    local cur="${COMP_WORDS[COMP_CWORD]}"
-    local branches=$(_git_wt_detach_branches)
-    COMPREPLY=($(compgen -W "${branches}" -- "${cur}"))
+    _git_wt_detach_complete 1
 }
 
 # Complete for direct command
@@ -33,9 +53,7 @@ complete -F _git_wt_detach git-wt-detach
 # Complete for git subcommand
 _git_wt_detach_subcommand() {
     if [[ ${COMP_WORDS[1]} == "wt-detach" ]]; then
-        local cur="${COMP_WORDS[COMP_CWORD]}"
-        local branches=$(_git_wt_detach_branches)
-        COMPREPLY=($(compgen -W "${branches}" -- "${cur}"))
+        _git_wt_detach_complete 2
     fi
 }
 
@@ -53,19 +71,39 @@ fi
 `
 
 const zshCompletion = `# zsh completion for git-wt-detach
+_git_wt_detach_commands=(
+    'detach:Detach or revert a branch, also one named like a command'
+    'gc:Delete orphaned temp branches'
+    'doctor:Diagnose and repair inconsistent detach state'
+    'swap:Swap branches between the current worktree and another one'
+    'move:Move a branch from another worktree to the current one for good'
+    'exec:Borrow a branch for the duration of a command'
+    'expire:Revert detaches whose deadline has passed'
+    'refresh:Fast-forward temp branches to their original branch'
+    'config:Show the effective configuration'
+)
+
+# Commands are only completed in the first position, and branches unless
+# the command takes none
 _git-wt-detach() {
     local -a branches
     branches=(${(f)"$(git worktree list --porcelain 2>/dev/null | grep '^branch ' | sed 's/^branch refs\/heads\///')"})
-    _describe 'branch' branches
+    local cmd=${${words[2,CURRENT-1]:#-*}[1]}
+    case $cmd in
+        '')
+            _describe 'branch' branches
+            _describe 'command' _git_wt_detach_commands
+            ;;
+        gc|doctor|expire|config) ;;
+        *) _describe 'branch' branches ;;
+    esac
 }
 
 compdef _git-wt-detach git-wt-detach
 
 # Also register as git subcommand
 _git-wt-detach-subcommand() {
-    local -a branches
-    branches=(${(f)"$(git worktree list --porcelain 2>/dev/null | grep '^branch ' | sed 's/^branch refs\/heads\///')"})
-    _describe 'branch' branches
+    _git-wt-detach
 }
 
 # Register completion for "git wt-detach"
@@ -77,12 +115,33 @@ function __fish_git_wt_detach_branches
     git worktree list --porcelain 2>/dev/null | grep '^branch ' | sed 's/^branch refs\/heads\///'
 end
 
-complete -c git-wt-detach -f -a '(__fish_git_wt_detach_branches)' -d 'Branch'
+complete -c git-wt-detach -n 'not __fish_seen_subcommand_from gc doctor expire config' -f -a '(__fish_git_wt_detach_branches)' -d 'Branch'
+complete -c git-wt-detach -n '__fish_use_subcommand' -f -a detach -d 'Detach or revert a branch, also one named like a command'
+complete -c git-wt-detach -n '__fish_use_subcommand' -f -a gc -d 'Delete orphaned temp branches'
+complete -c git-wt-detach -n '__fish_use_subcommand' -f -a doctor -d 'Diagnose and repair inconsistent detach state'
+complete -c git-wt-detach -n '__fish_seen_subcommand_from doctor' -l fix -d 'Apply the suggested fixes'
+complete -c git-wt-detach -n '__fish_use_subcommand' -f -a swap -d 'Swap branches between the current worktree and another one'
+complete -c git-wt-detach -n '__fish_use_subcommand' -f -a move -d 'Move a branch from another worktree to the current one for good'
+complete -c git-wt-detach -n '__fish_seen_subcommand_from move' -l fallback -x -a 'detached default' -d 'What the other worktree is switched to'
+complete -c git-wt-detach -n '__fish_use_subcommand' -f -a exec -d 'Borrow a branch for the duration of a command'
+complete -c git-wt-detach -n '__fish_use_subcommand' -f -a expire -d 'Revert detaches whose deadline has passed'
+complete -c git-wt-detach -n '__fish_use_subcommand' -f -a refresh -d 'Fast-forward temp branches to their original branch'
+complete -c git-wt-detach -n '__fish_use_subcommand' -f -a config -d 'Show the effective configuration'
 complete -c git-wt-detach -s n -l dry-run -d 'Show what would be done without making changes'
 complete -c git-wt-detach -s r -l revert -d 'Revert the temporary detach'
 complete -c git-wt-detach -s f -l force -d 'Force execution even with uncommitted changes'
 complete -c git-wt-detach -s y -l yes -d 'Skip confirmation prompt'
 complete -c git-wt-detach -s c -l checkout -d 'Checkout the branch after detaching'
+complete -c git-wt-detach -s t -l track -d 'Copy the upstream tracking config to the temp branch'
+complete -c git-wt-detach -l give -x -a '(git worktree list --porcelain 2>/dev/null | string replace -f -r "^worktree " "")' -d 'Give the current branch to another worktree'
+complete -c git-wt-detach -l to -x -a '(git worktree list --porcelain 2>/dev/null | string replace -f -r "^worktree " "")' -d 'Checkout the branch in another worktree'
+complete -c git-wt-detach -s w -l worktree -x -a '(git worktree list --porcelain 2>/dev/null | string replace -f -r "^worktree " "")' -d 'Detach or revert the branch of this worktree'
+complete -c git-wt-detach -l keep-going -d 'Keep detaching the remaining branches when one fails'
+complete -c git-wt-detach -l stdin -d 'Read branches to detach or revert from stdin'
+complete -c git-wt-detach -l for -x -d 'Revert the detach with expire after this long'
+complete -c git-wt-detach -l until -x -d 'Revert the detach with expire after this time'
+complete -c git-wt-detach -l temp-branch -x -a '(git for-each-ref --format="%(refname:short)" refs/heads/ 2>/dev/null)' -d 'Temp branch to revert'
+complete -c git-wt-detach -l no-dry-run -l no-force -l no-yes -l no-checkout -l no-track -l no-keep-going -d 'Override the configured default'
 complete -c git-wt-detach -l version -d 'Show version'
 
 # git subcommand completion
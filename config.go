@@ -0,0 +1,69 @@
+package wtdetach
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Setting is a wt-detach.* setting that is not a command-line flag. Flags
+// tagged config:"<key>" are settings too.
+type Setting struct {
+	Key     string
+	Default string
+	Help    string
+}
+
+// Settings lists the settings that are not command-line flags
+var Settings = []Setting{
+	{Key: "suffix", Default: DefaultSuffix, Help: "Suffix of temporary branch names."},
+	{Key: "nameTemplate", Help: "Template for temporary branch names, instead of the suffix."},
+	{Key: "confirm", Default: ConfirmAlways, Help: "When to ask before switching worktrees: always, never, or dirty-only."},
+}
+
+// ConfigEnv returns the environment variable that overrides wt-detach.<key>,
+// like GIT_WT_DETACH_NAME_TEMPLATE for nameTemplate
+func ConfigEnv(key string) string {
+	var b strings.Builder
+	b.WriteString("GIT_WT_DETACH_")
+	for i, r := range key {
+		if unicode.IsUpper(r) && i > 0 {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// LookupConfig returns the value of wt-detach.<key> and where it comes from:
+// the GIT_WT_DETACH_* environment variable if set, or else git config, where
+// the repository overrides the global and system config. The source is the
+// variable name or the git config scope, like "local" or "global".
+func (d *Detacher) LookupConfig(key string) (value, source string, ok bool) {
+	env := ConfigEnv(key)
+	if value, ok := os.LookupEnv(env); ok {
+		return value, env, true
+	}
+	output, err := d.git.Run("config", "--show-scope", "--get", "wt-detach."+key)
+	if err != nil {
+		return "", "", false
+	}
+	source, value, _ = strings.Cut(output, "\t")
+	return value, source, true
+}
+
+// ParseConfigBool parses a boolean the way git config does
+func ParseConfigBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "true", "yes", "on":
+		return true, nil
+	case "false", "no", "off", "":
+		return false, nil
+	}
+	if n, err := strconv.Atoi(value); err == nil {
+		return n != 0, nil
+	}
+	return false, fmt.Errorf("invalid boolean '%s'", value)
+}
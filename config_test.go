@@ -0,0 +1,138 @@
+package wtdetach
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/kong"
+)
+
+func TestConfigEnv(t *testing.T) {
+	tests := map[string]string{
+		"force":        "GIT_WT_DETACH_FORCE",
+		"nameTemplate": "GIT_WT_DETACH_NAME_TEMPLATE",
+		"keepGoing":    "GIT_WT_DETACH_KEEP_GOING",
+		"moveFallback": "GIT_WT_DETACH_MOVE_FALLBACK",
+	}
+	for key, want := range tests {
+		if got := ConfigEnv(key); got != want {
+			t.Errorf("ConfigEnv(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestParseConfigBool(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    bool
+		wantErr bool
+	}{
+		{"true", true, false},
+		{"Yes", true, false},
+		{"on", true, false},
+		{"1", true, false},
+		{"false", false, false},
+		{"off", false, false},
+		{"0", false, false},
+		{"", false, false},
+		{"maybe", false, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseConfigBool(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseConfigBool(%q) = %v, %v", tt.value, got, err)
+		}
+	}
+}
+
+func TestIntegration_LookupConfig(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+	if _, _, ok := d.LookupConfig("suffix"); ok {
+		t.Fatal("suffix should be unset")
+	}
+
+	runGit(t, repoDir, "config", "--global", "wt-detach.suffix", "__global")
+	if value, source, _ := d.LookupConfig("suffix"); value != "__global" || source != "global" {
+		t.Errorf("expected __global from global, got %q from %q", value, source)
+	}
+
+	runGit(t, repoDir, "config", "wt-detach.suffix", "__local")
+	if value, source, _ := d.LookupConfig("suffix"); value != "__local" || source != "local" {
+		t.Errorf("expected __local from local, got %q from %q", value, source)
+	}
+
+	t.Setenv("GIT_WT_DETACH_SUFFIX", "__env")
+	if value, source, _ := d.LookupConfig("suffix"); value != "__env" || source != "GIT_WT_DETACH_SUFFIX" {
+		t.Errorf("expected __env from the environment, got %q from %q", value, source)
+	}
+	d.LoadSuffixFromConfig()
+	if d.GetSuffix() != "__env" {
+		t.Errorf("suffix should be loaded from the environment, got %q", d.GetSuffix())
+	}
+
+	// Test: booleans from the environment override git config
+	runGit(t, repoDir, "config", "wt-detach.track", "true")
+	if !d.ConfigBool("track") {
+		t.Error("track should be true")
+	}
+	t.Setenv("GIT_WT_DETACH_TRACK", "off")
+	if d.ConfigBool("track") {
+		t.Error("track should be overridden by the environment")
+	}
+}
+
+func TestIntegration_ConfigResolver(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	runGit(t, repoDir, "config", "wt-detach.checkout", "true")
+	runGit(t, repoDir, "config", "wt-detach.moveFallback", "main")
+	t.Setenv("GIT_WT_DETACH_FORCE", "1")
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	parse := func(args ...string) *CLI {
+		t.Helper()
+		cli := &CLI{}
+		parser, err := kong.New(cli, kong.Resolvers(cli.ConfigResolver()), kong.Exit(func(int) {}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := parser.Parse(args); err != nil {
+			t.Fatalf("Parse(%v) failed: %v", args, err)
+		}
+		return cli
+	}
+
+	cli := parse("feature")
+	if !cli.Detach.Checkout || !cli.Force || cli.Yes {
+		t.Errorf("unexpected flags: checkout=%v force=%v yes=%v", cli.Detach.Checkout, cli.Force, cli.Yes)
+	}
+	if cli.configured["checkout"] != "local" || cli.configured["force"] != "GIT_WT_DETACH_FORCE" {
+		t.Errorf("unexpected sources: %v", cli.configured)
+	}
+
+	// Test: the command line wins
+	if cli := parse("feature", "--no-checkout", "--no-force"); cli.Detach.Checkout || cli.Force {
+		t.Error("--no-checkout and --no-force should override the configuration")
+	}
+
+	// Test: a flag the configured one cannot be combined with
+	if cli := parse("feature", "--to", filepath.Join(repoDir, "other")); cli.Detach.Checkout {
+		t.Error("configured --checkout should be left alone with --to")
+	}
+
+	if cli := parse("move", "feature"); cli.Move.Fallback != "main" {
+		t.Errorf("expected fallback from moveFallback, got %q", cli.Move.Fallback)
+	}
+}
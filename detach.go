@@ -1,21 +1,41 @@
 package wtdetach
 
 import (
+	"errors"
 	"fmt"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
 const (
 	// DefaultSuffix is the default suffix for temporary branches
 	DefaultSuffix = "__wt_detach"
+
+	// lockReasonPrefix and lockReasonFormat make up the reason recorded when
+	// locking the target worktree
+	lockReasonPrefix = "wt-detach: "
+	lockReasonFormat = lockReasonPrefix + "%s borrowed from here"
+
+	// DisabledPushRemote is set as the push remote of temp branches that track
+	// an upstream, so that a plain `git push` fails instead of publishing them
+	DisabledPushRemote = "wt-detach-push-disabled"
 )
 
 // Options holds the command options
 type Options struct {
-	DryRun bool
-	Revert bool
-	Force  bool
-	Yes    bool
+	DryRun     bool
+	Revert     bool
+	Force      bool
+	Yes        bool
+	Track      bool
+	Checkout   bool
+	Give       string    // worktree to give the branch checked out here to
+	To         string    // worktree to check the detached branch out in
+	Worktree   string    // worktree to detach the branch from
+	KeepGoing  bool      // keep detaching the rest of a batch after a failure
+	Expires    time.Time // when the detach should be reverted, zero for never
+	TempBranch string    // overrides the temporary branch to revert
 }
 
 // Result represents the result of an operation
@@ -24,12 +44,31 @@ type Result struct {
 	Message      string
 	WorktreePath string
 	TempBranch   string
+	Locked       bool
+	Unlocked     bool
+	Tracking     bool
+
+	// CheckoutPath is the worktree the branch was checked out in after detaching
+	CheckoutPath string
+	// ReleasedPath is the worktree that was switched away from the branch on
+	// revert, and ReleasedTo what it was switched to
+	ReleasedPath string
+	ReleasedTo   string
+
+	// Snapshot is the ref holding a copy of the uncommitted changes carried
+	// to the temp branch on detach
+	Snapshot string
+	// Conflict is set if uncommitted changes could not be restored on revert
+	Conflict *SnapshotConflict
+	// HookErr is set if the post hook failed, after the operation succeeded
+	HookErr error
 }
 
 // Detacher handles the detach/revert operations
 type Detacher struct {
-	git    *Git
-	suffix string
+	git      *Git
+	suffix   string
+	template string
 }
 
 // NewDetacher creates a new Detacher
@@ -54,12 +93,31 @@ func (d *Detacher) GetSuffix() string {
 
 // LoadSuffixFromConfig loads the suffix from git config
 func (d *Detacher) LoadSuffixFromConfig() {
-	if suffix, err := d.git.Run("config", "--get", "wt-detach.suffix"); err == nil && suffix != "" {
+	if suffix := d.ConfigString("suffix"); suffix != "" {
 		d.suffix = suffix
 	}
 }
 
-// TempBranchName returns the temporary branch name for a given branch
+// ConfigString returns the value of wt-detach.<key> from git config, or its
+// GIT_WT_DETACH_* override
+func (d *Detacher) ConfigString(key string) string {
+	value, _, _ := d.LookupConfig(key)
+	return value
+}
+
+// ConfigBool returns the boolean value of wt-detach.<key> from git config, or
+// its GIT_WT_DETACH_* override
+func (d *Detacher) ConfigBool(key string) bool {
+	value, _, ok := d.LookupConfig(key)
+	if !ok {
+		return false
+	}
+	b, err := ParseConfigBool(value)
+	return err == nil && b
+}
+
+// TempBranchName returns the temporary branch name for a given branch using the suffix.
+// Use TempBranchNameFor to honor the name template.
 func (d *Detacher) TempBranchName(branch string) string {
 	return branch + d.suffix
 }
@@ -70,6 +128,22 @@ func (d *Detacher) BranchExists(branch string) bool {
 	return err == nil
 }
 
+// ListBranches returns the names of all local branches
+func (d *Detacher) ListBranches() ([]string, error) {
+	output, err := d.git.Run("for-each-ref", "--format=%(refname)", "refs/heads/")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+
+	var branches []string
+	for _, ref := range strings.Split(output, "\n") {
+		if name := strings.TrimPrefix(ref, "refs/heads/"); name != "" {
+			branches = append(branches, name)
+		}
+	}
+	return branches, nil
+}
+
 // GetCurrentWorktreePath returns the path of the current worktree
 func (d *Detacher) GetCurrentWorktreePath() (string, error) {
 	path, err := d.git.Run("rev-parse", "--show-toplevel")
@@ -88,6 +162,22 @@ func (d *Detacher) ListWorktrees() ([]Worktree, error) {
 	return ParseWorktreeList(output), nil
 }
 
+// ResolveBranchWorktree finds a worktree by its path or basename and checks
+// that it has a branch checked out
+func (d *Detacher) ResolveBranchWorktree(spec string) (*Worktree, error) {
+	wt, err := d.ResolveWorktree(spec)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case wt.Bare:
+		return nil, fmt.Errorf("worktree %s is a bare repository and has no branch checked out", wt.Path)
+	case wt.Branch == "":
+		return nil, fmt.Errorf("worktree %s is not on a branch (HEAD is detached)", wt.Path)
+	}
+	return wt, nil
+}
+
 // FindWorktreeForBranch finds a worktree that has the specified branch checked out
 // It excludes the current worktree
 func (d *Detacher) FindWorktreeForBranch(branch string) (*Worktree, error) {
@@ -104,6 +194,65 @@ func (d *Detacher) FindWorktreeForBranch(branch string) (*Worktree, error) {
 	return FindWorktreeByBranch(worktrees, branch, currentPath), nil
 }
 
+// ResolveWorktree finds a worktree by its path or basename, either of which
+// may be a glob pattern matching exactly one worktree
+func (d *Detacher) ResolveWorktree(spec string) (*Worktree, error) {
+	worktrees, err := d.ListWorktrees()
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := filepath.Abs(spec)
+	if err != nil {
+		return nil, err
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+
+	matches := MatchWorktrees(worktrees, path, spec)
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("worktree '%s' not found", spec)
+	case 1:
+		return &matches[0], nil
+	default:
+		paths := make([]string, len(matches))
+		for i, wt := range matches {
+			paths[i] = wt.Path
+		}
+		return nil, fmt.Errorf("worktree '%s' is ambiguous: %s", spec, strings.Join(paths, ", "))
+	}
+}
+
+// FindWorktreeForTempBranch finds a worktree that has the specified temporary
+// branch checked out. Unlike FindWorktreeForBranch, it includes the current
+// worktree so that revert can be run from inside the detached worktree.
+func (d *Detacher) FindWorktreeForTempBranch(tmpBranch string) (*Worktree, error) {
+	worktrees, err := d.ListWorktrees()
+	if err != nil {
+		return nil, err
+	}
+	return FindWorktreeByBranch(worktrees, tmpBranch, ""), nil
+}
+
+// PruneWorktrees removes the administrative files of missing worktrees
+func (d *Detacher) PruneWorktrees() error {
+	if _, err := d.git.Run("worktree", "prune"); err != nil {
+		return fmt.Errorf("failed to prune worktrees: %w", err)
+	}
+	return nil
+}
+
+// RemoveMissingWorktree removes the administrative files of a worktree whose
+// directory is missing, unlike PruneWorktrees leaving other worktrees alone
+func (d *Detacher) RemoveMissingWorktree(path string) error {
+	if _, err := d.git.Run("worktree", "remove", path); err != nil {
+		return fmt.Errorf("failed to remove missing worktree '%s': %w", path, err)
+	}
+	return nil
+}
+
 // HasUncommittedChanges checks if a worktree has uncommitted changes
 func (d *Detacher) HasUncommittedChanges(worktreePath string) bool {
 	output, err := d.git.RunInDir(worktreePath, "status", "--porcelain")
@@ -133,6 +282,17 @@ func (d *Detacher) GetUncommittedFiles(worktreePath string) []string {
 	return files
 }
 
+// CurrentHead returns the branch and commit checked out in a worktree.
+// The branch is empty when HEAD is detached.
+func (d *Detacher) CurrentHead(worktreePath string) (branch, head string, err error) {
+	head, err = d.git.RunInDir(worktreePath, "rev-parse", "HEAD")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get HEAD of '%s': %w", worktreePath, err)
+	}
+	branch, _ = d.git.RunInDir(worktreePath, "symbolic-ref", "--quiet", "--short", "HEAD")
+	return branch, head, nil
+}
+
 // CreateBranch creates a new branch at the current HEAD of a worktree
 func (d *Detacher) CreateBranch(branch, worktreePath string) error {
 	if _, err := d.git.RunInDir(worktreePath, "branch", branch); err != nil {
@@ -141,6 +301,28 @@ func (d *Detacher) CreateBranch(branch, worktreePath string) error {
 	return nil
 }
 
+// CopyUpstream copies the upstream tracking config of branch to tmpBranch.
+// The push remote of tmpBranch is disabled so that it is never pushed by accident.
+// The copied config is removed together with tmpBranch.
+func (d *Detacher) CopyUpstream(branch, tmpBranch string) (bool, error) {
+	remote := d.getBranchConfig(branch, "remote")
+	merge := d.getBranchConfig(branch, "merge")
+	if remote == "" || merge == "" {
+		return false, nil
+	}
+
+	if err := d.setBranchConfig(tmpBranch, "remote", remote); err != nil {
+		return false, err
+	}
+	if err := d.setBranchConfig(tmpBranch, "merge", merge); err != nil {
+		return false, err
+	}
+	if err := d.setBranchConfig(tmpBranch, "pushRemote", DisabledPushRemote); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // DeleteBranch deletes a branch
 func (d *Detacher) DeleteBranch(branch string) error {
 	if _, err := d.git.Run("branch", "-D", branch); err != nil {
@@ -157,15 +339,48 @@ func (d *Detacher) Checkout(worktreePath, branch string) error {
 	return nil
 }
 
-// Detach performs the detach operation
+// CheckoutDetached detaches HEAD of a worktree at the given commit
+func (d *Detacher) CheckoutDetached(worktreePath, commit string) error {
+	if _, err := d.git.RunInDir(worktreePath, "checkout", "--detach", commit); err != nil {
+		return fmt.Errorf("failed to detach HEAD at '%s' in '%s': %w", commit, worktreePath, err)
+	}
+	return nil
+}
+
+// LockReason returns the lock reason used for a worktree whose branch is borrowed
+func LockReason(branch string) string {
+	return fmt.Sprintf(lockReasonFormat, branch)
+}
+
+// IsOwnLock reports whether a worktree is locked by wt-detach
+func IsOwnLock(wt *Worktree) bool {
+	return wt.Locked && strings.HasPrefix(wt.LockReason, lockReasonPrefix)
+}
+
+// LockWorktree locks a worktree so that it is not pruned or removed
+func (d *Detacher) LockWorktree(worktreePath, reason string) error {
+	if _, err := d.git.Run("worktree", "lock", "--reason", reason, worktreePath); err != nil {
+		return fmt.Errorf("failed to lock worktree '%s': %w", worktreePath, err)
+	}
+	return nil
+}
+
+// UnlockWorktree unlocks a worktree
+func (d *Detacher) UnlockWorktree(worktreePath string) error {
+	if _, err := d.git.Run("worktree", "unlock", worktreePath); err != nil {
+		return fmt.Errorf("failed to unlock worktree '%s': %w", worktreePath, err)
+	}
+	return nil
+}
+
+// Detach performs the detach operation. If the branch cannot be checked out
+// afterwards, the detach stands and its Result is returned with the error.
 func (d *Detacher) Detach(branch string, opts *Options) (*Result, error) {
 	if !d.BranchExists(branch) {
 		return nil, fmt.Errorf("branch '%s' does not exist", branch)
 	}
 
-	tmpBranch := d.TempBranchName(branch)
-
-	wt, err := d.FindWorktreeForBranch(branch)
+	wt, err := d.FindDetachWorktree(branch, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -183,8 +398,23 @@ func (d *Detacher) Detach(branch string, opts *Options) (*Result, error) {
 		}
 	}
 
-	if d.BranchExists(tmpBranch) {
-		return nil, fmt.Errorf("temporary branch '%s' already exists. Use --revert first or delete the branch manually", tmpBranch)
+	checkoutPath, err := d.FindCheckoutWorktree(wt, opts)
+	if err != nil {
+		return nil, err
+	}
+	if (opts.Give != "" || opts.To != "") && d.HasUncommittedChanges(checkoutPath) {
+		if !opts.Force {
+			return nil, fmt.Errorf("uncommitted changes found in worktree: %s\n  Use --force to override", checkoutPath)
+		}
+	}
+
+	tmpBranch, err := d.TempBranchNameFor(branch, wt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.checkTempBranchFree(tmpBranch); err != nil {
+		return nil, err
 	}
 
 	if opts.DryRun {
@@ -196,21 +426,218 @@ func (d *Detacher) Detach(branch string, opts *Options) (*Result, error) {
 		}, nil
 	}
 
+	hookEnv := &HookEnv{Branch: branch, TempBranch: tmpBranch, Worktree: wt.Path, CheckoutWorktree: checkoutPath}
+	if err := d.RunHook(HookDetachPre, hookEnv); err != nil {
+		return nil, err
+	}
+
 	if err := d.CreateBranch(tmpBranch, wt.Path); err != nil {
 		return nil, err
 	}
 
+	var tracking bool
+	if opts.Track {
+		if tracking, err = d.CopyUpstream(branch, tmpBranch); err != nil {
+			d.DeleteBranch(tmpBranch)
+			return nil, err
+		}
+	}
+
+	// Uncommitted changes are carried to the temp branch, which is at the
+	// same commit. The snapshot is only a backup of their staged/unstaged
+	// split: revert takes a fresh one of the changes as they are by then, and
+	// the backup goes away with the temp branch.
+	var snapshot string
+	if opts.Force {
+		commit, err := d.Snapshot(wt.Path, SnapshotRef(tmpBranch))
+		if err != nil {
+			d.DeleteBranch(tmpBranch)
+			return nil, err
+		}
+		if commit != "" {
+			snapshot = SnapshotRef(tmpBranch)
+		}
+	}
+
 	if err := d.Checkout(wt.Path, tmpBranch); err != nil {
+		d.DeleteSnapshot(snapshot)
 		d.DeleteBranch(tmpBranch)
 		return nil, err
 	}
 
-	return &Result{
+	// The main worktree cannot be locked, and a worktree locked by someone
+	// else is left as is so that revert does not remove their lock.
+	st := &State{TempBranch: tmpBranch, Origin: branch, Worktree: wt.Path, Expires: opts.Expires}
+	if !wt.Main && !wt.Locked {
+		if err := d.LockWorktree(wt.Path, LockReason(branch)); err != nil {
+			d.Checkout(wt.Path, branch)
+			d.DeleteSnapshot(snapshot)
+			d.DeleteBranch(tmpBranch)
+			return nil, err
+		}
+		st.Locked = true
+	}
+	if err := d.SaveState(st); err != nil {
+		if st.Locked {
+			d.UnlockWorktree(wt.Path)
+		}
+		d.Checkout(wt.Path, branch)
+		d.DeleteSnapshot(snapshot)
+		d.DeleteBranch(tmpBranch)
+		return nil, err
+	}
+
+	result := &Result{
 		Success:      true,
 		Message:      fmt.Sprintf("Branch '%s' detached successfully", branch),
 		WorktreePath: wt.Path,
 		TempBranch:   tmpBranch,
-	}, nil
+		Locked:       st.Locked,
+		Tracking:     tracking,
+		Snapshot:     snapshot,
+	}
+
+	if checkoutPath != "" {
+		if err := d.checkoutAndRecord(checkoutPath, branch, st); err != nil {
+			return result, err
+		}
+		result.CheckoutPath = checkoutPath
+	}
+
+	result.HookErr = d.RunHook(HookDetachPost, hookEnv)
+	return result, nil
+}
+
+// checkTempBranchFree fails if tmpBranch is taken: the branch exists, or
+// changes that a revert could not restore are still kept at its snapshot
+// ref, where a new detach would overwrite them
+func (d *Detacher) checkTempBranchFree(tmpBranch string) error {
+	if d.BranchExists(tmpBranch) {
+		return fmt.Errorf("temporary branch '%s' already exists. Use --revert first or delete the branch manually", tmpBranch)
+	}
+	if ref := SnapshotRef(tmpBranch); d.SnapshotExists(ref) {
+		return fmt.Errorf("uncommitted changes that a revert could not restore are still kept at %s\n  Apply them with:\n    git stash apply --index %s\n  and drop them afterwards with:\n    git update-ref -d %s", ref, ref, ref)
+	}
+	return nil
+}
+
+// FindDetachWorktree finds the worktree to detach a branch from: the named
+// worktree, the current worktree when giving the branch away, any worktree
+// when checking it out in a named one, or else another worktree that has it
+// checked out
+func (d *Detacher) FindDetachWorktree(branch string, opts *Options) (*Worktree, error) {
+	switch {
+	case opts.Worktree != "":
+		wt, err := d.ResolveBranchWorktree(opts.Worktree)
+		if err != nil {
+			return nil, err
+		}
+		if wt.Branch != branch {
+			return nil, fmt.Errorf("worktree %s has '%s' checked out, not '%s'", wt.Path, wt.Branch, branch)
+		}
+		return wt, nil
+	case opts.To != "":
+		// The current directory need not be a worktree
+		worktrees, err := d.ListWorktrees()
+		if err != nil {
+			return nil, err
+		}
+		return FindWorktreeByBranch(worktrees, branch, ""), nil
+	case opts.Give == "":
+		return d.FindWorktreeForBranch(branch)
+	}
+
+	currentPath, err := d.GetCurrentWorktreePath()
+	if err != nil {
+		return nil, err
+	}
+	worktrees, err := d.ListWorktrees()
+	if err != nil {
+		return nil, err
+	}
+	for _, wt := range worktrees {
+		if wt.Path != currentPath {
+			continue
+		}
+		if wt.Branch != branch {
+			return nil, fmt.Errorf("branch '%s' is not checked out in the current worktree", branch)
+		}
+		return &wt, nil
+	}
+	return nil, fmt.Errorf("current worktree not found: %s", currentPath)
+}
+
+// FindCheckoutWorktree returns the path of the worktree the branch is checked
+// out in after detaching it from wt, or empty if it is not checked out
+func (d *Detacher) FindCheckoutWorktree(wt *Worktree, opts *Options) (string, error) {
+	var path string
+	switch {
+	case opts.Give != "" || opts.To != "":
+		spec := opts.Give
+		if spec == "" {
+			spec = opts.To
+		}
+		target, err := d.ResolveWorktree(spec)
+		if err != nil {
+			return "", err
+		}
+		if target.Bare {
+			return "", fmt.Errorf("worktree %s is a bare repository and cannot check out a branch", target.Path)
+		}
+		path = target.Path
+	case opts.Checkout:
+		currentPath, err := d.GetCurrentWorktreePath()
+		if err != nil {
+			return "", err
+		}
+		path = currentPath
+	default:
+		return "", nil
+	}
+
+	if path == wt.Path {
+		return "", fmt.Errorf("branch is already checked out in worktree: %s", wt.Path)
+	}
+	return path, nil
+}
+
+// checkoutAndRecord checks out branch in a worktree and records what the
+// worktree had checked out before, so that revert can switch it back
+func (d *Detacher) checkoutAndRecord(worktreePath, branch string, st *State) error {
+	prevBranch, prevHead, err := d.CurrentHead(worktreePath)
+	if err != nil {
+		return err
+	}
+	if err := d.Checkout(worktreePath, branch); err != nil {
+		return err
+	}
+
+	st.CheckoutWorktree = worktreePath
+	st.PrevBranch = prevBranch
+	st.PrevHead = prevHead
+	return d.SaveState(st)
+}
+
+// release switches a worktree that has the original branch checked out back
+// to what it had checked out before, as recorded in st. It returns what the
+// worktree was switched to.
+func (d *Detacher) release(worktreePath string, st *State, worktrees []Worktree) (string, error) {
+	if st.PrevBranch != "" && d.BranchExists(st.PrevBranch) &&
+		FindWorktreeByBranch(worktrees, st.PrevBranch, worktreePath) == nil {
+		if err := d.Checkout(worktreePath, st.PrevBranch); err != nil {
+			return "", err
+		}
+		return st.PrevBranch, nil
+	}
+
+	commit := st.PrevHead
+	if commit == "" {
+		commit = "HEAD"
+	}
+	if err := d.CheckoutDetached(worktreePath, commit); err != nil {
+		return "", err
+	}
+	return "detached HEAD", nil
 }
 
 // Revert performs the revert operation
@@ -219,17 +646,18 @@ func (d *Detacher) Revert(branch string, opts *Options) (*Result, error) {
 		return nil, fmt.Errorf("branch '%s' does not exist", branch)
 	}
 
-	tmpBranch := d.TempBranchName(branch)
-
-	if !d.BranchExists(tmpBranch) {
-		return nil, fmt.Errorf("temporary branch '%s' does not exist", tmpBranch)
+	tmpBranch, err := d.resolveTempBranch(branch, opts.TempBranch)
+	if err != nil {
+		return nil, err
 	}
 
-	wt, err := d.FindWorktreeForBranch(tmpBranch)
+	wt, err := d.FindWorktreeForTempBranch(tmpBranch)
 	if err != nil {
 		return nil, err
 	}
 
+	st := d.LoadState(tmpBranch)
+
 	if wt == nil {
 		if opts.DryRun {
 			return &Result{
@@ -239,33 +667,95 @@ func (d *Detacher) Revert(branch string, opts *Options) (*Result, error) {
 			}, nil
 		}
 
+		hookEnv := &HookEnv{Branch: branch, TempBranch: tmpBranch}
+		if err := d.RunHook(HookRevertPre, hookEnv); err != nil {
+			return nil, err
+		}
+
+		unlocked, err := d.unlockIfOwned(st)
+		if err != nil {
+			return nil, err
+		}
+
 		if err := d.DeleteBranch(tmpBranch); err != nil {
 			return nil, err
 		}
+		d.DeleteSnapshot(SnapshotRef(tmpBranch))
 
 		return &Result{
 			Success:    true,
 			Message:    fmt.Sprintf("Deleted temporary branch '%s'", tmpBranch),
 			TempBranch: tmpBranch,
+			Unlocked:   unlocked,
+			HookErr:    d.RunHook(HookRevertPost, hookEnv),
 		}, nil
 	}
 
+	// The original branch cannot be checked out in the target worktree while
+	// it is checked out elsewhere. The worktree it was checked out in by
+	// --checkout is switched back first; any other one is left to the user.
+	holder, err := d.FindBranchHolder(branch, st, wt.Path)
+	if err != nil {
+		return nil, err
+	}
+
 	if d.HasUncommittedChanges(wt.Path) {
 		if !opts.Force {
 			return nil, fmt.Errorf("uncommitted changes found in worktree: %s\n  Use --force to override", wt.Path)
 		}
 	}
+	if holder != nil && d.HasUncommittedChanges(holder.Path) {
+		if !opts.Force {
+			return nil, fmt.Errorf("uncommitted changes found in worktree: %s\n  Use --force to override", holder.Path)
+		}
+	}
 
 	if opts.DryRun {
-		return &Result{
+		result := &Result{
 			Success:      true,
 			Message:      "dry-run",
 			WorktreePath: wt.Path,
 			TempBranch:   tmpBranch,
-		}, nil
+		}
+		if holder != nil {
+			result.ReleasedPath = holder.Path
+		}
+		return result, nil
 	}
 
-	if err := d.Checkout(wt.Path, branch); err != nil {
+	hookEnv := &HookEnv{Branch: branch, TempBranch: tmpBranch, Worktree: wt.Path}
+	if holder != nil {
+		hookEnv.CheckoutWorktree = holder.Path
+	}
+	if err := d.RunHook(HookRevertPre, hookEnv); err != nil {
+		return nil, err
+	}
+
+	var releasedTo string
+	if holder != nil {
+		worktrees, err := d.ListWorktrees()
+		if err != nil {
+			return nil, err
+		}
+		if releasedTo, err = d.release(holder.Path, st, worktrees); err != nil {
+			return nil, err
+		}
+	}
+
+	// Restores the exact staged/unstaged split of uncommitted changes. If they
+	// conflict with the original branch, they are kept at the snapshot ref
+	// and the revert is completed without them.
+	err = d.checkoutWithSnapshot(wt.Path, branch, SnapshotRef(tmpBranch))
+	var conflict *SnapshotConflict
+	if err != nil && !errors.As(err, &conflict) {
+		if holder != nil {
+			d.Checkout(holder.Path, branch)
+		}
+		return nil, err
+	}
+
+	unlocked, err := d.unlockIfOwned(st)
+	if err != nil {
 		return nil, err
 	}
 
@@ -273,10 +763,59 @@ func (d *Detacher) Revert(branch string, opts *Options) (*Result, error) {
 		return nil, err
 	}
 
-	return &Result{
+	result := &Result{
 		Success:      true,
 		Message:      fmt.Sprintf("Branch '%s' restored successfully", branch),
 		WorktreePath: wt.Path,
 		TempBranch:   tmpBranch,
-	}, nil
+		Unlocked:     unlocked,
+		Conflict:     conflict,
+	}
+	if holder != nil {
+		result.ReleasedPath = holder.Path
+		result.ReleasedTo = releasedTo
+	}
+	result.HookErr = d.RunHook(HookRevertPost, hookEnv)
+	return result, nil
+}
+
+// FindBranchHolder returns the worktree other than excludePath that has the
+// original branch of st checked out. It fails if that worktree is not the one
+// the branch was checked out in after detaching.
+func (d *Detacher) FindBranchHolder(branch string, st *State, excludePath string) (*Worktree, error) {
+	worktrees, err := d.ListWorktrees()
+	if err != nil {
+		return nil, err
+	}
+
+	holder := FindWorktreeByBranch(worktrees, branch, excludePath)
+	if holder == nil {
+		return nil, nil
+	}
+	if holder.Path != st.CheckoutWorktree {
+		return nil, fmt.Errorf("branch '%s' is checked out in worktree: %s\n  Switch that worktree to another branch first", branch, holder.Path)
+	}
+	return holder, nil
+}
+
+// unlockIfOwned unlocks the worktree recorded in st if wt-detach locked it
+// and it is still locked with our reason. It reports whether it unlocked.
+func (d *Detacher) unlockIfOwned(st *State) (bool, error) {
+	if !st.Locked || st.Worktree == "" {
+		return false, nil
+	}
+
+	worktrees, err := d.ListWorktrees()
+	if err != nil {
+		return false, err
+	}
+	for _, wt := range worktrees {
+		if wt.Path == st.Worktree && IsOwnLock(&wt) {
+			if err := d.UnlockWorktree(wt.Path); err != nil {
+				return false, err
+			}
+			return true, nil
+		}
+	}
+	return false, nil
 }
@@ -526,3 +526,699 @@ func TestFormatUncommittedError(t *testing.T) {
 		t.Errorf("error should not list individual files when > 10: %s", errMsg)
 	}
 }
+
+// worktreeAt returns the worktree at path as listed by the detacher
+func worktreeAt(t *testing.T, d *Detacher, path string) Worktree {
+	t.Helper()
+	worktrees, err := d.ListWorktrees()
+	if err != nil {
+		t.Fatalf("failed to list worktrees: %v", err)
+	}
+	for _, wt := range worktrees {
+		if wt.Path == path {
+			return wt
+		}
+	}
+	t.Fatalf("worktree not found: %s", path)
+	return Worktree{}
+}
+
+func TestIntegration_LockWorktreeWhileDetached(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature-lock")
+	worktreeDir := filepath.Join(resolvePath(t, t.TempDir()), "worktree-lock")
+	createWorktree(t, repoDir, worktreeDir, "feature-lock")
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+
+	result, err := d.Detach("feature-lock", &Options{Yes: true})
+	if err != nil {
+		t.Fatalf("Detach failed: %v", err)
+	}
+	if !result.Locked {
+		t.Error("Detach should report that the worktree was locked")
+	}
+
+	// Verify: worktree should be locked with the wt-detach reason
+	wt := worktreeAt(t, d, worktreeDir)
+	if !wt.Locked {
+		t.Fatal("worktree should be locked while detached")
+	}
+	if wt.LockReason != "wt-detach: feature-lock borrowed from here" {
+		t.Errorf("unexpected lock reason: %q", wt.LockReason)
+	}
+
+	result, err = d.Revert("feature-lock", &Options{Yes: true})
+	if err != nil {
+		t.Fatalf("Revert failed: %v", err)
+	}
+	if !result.Unlocked {
+		t.Error("Revert should report that the worktree was unlocked")
+	}
+
+	// Verify: worktree should be unlocked after revert
+	if wt := worktreeAt(t, d, worktreeDir); wt.Locked {
+		t.Error("worktree should be unlocked after revert")
+	}
+}
+
+func TestIntegration_DetachRollbackOnSaveStateFailure(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature-nostate")
+	worktreeDir := filepath.Join(resolvePath(t, t.TempDir()), "worktree-nostate")
+	createWorktree(t, repoDir, worktreeDir, "feature-nostate")
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	// Held by another git process, so the state cannot be saved
+	configLock := filepath.Join(repoDir, ".git", "config.lock")
+	if err := os.WriteFile(configLock, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDetacher()
+	if _, err := d.Detach("feature-nostate", &Options{Yes: true}); err == nil {
+		t.Fatal("Detach should fail when the state cannot be saved")
+	}
+	os.Remove(configLock)
+
+	if branchExistsInRepo(t, repoDir, "feature-nostate__wt_detach") {
+		t.Error("temp branch should be deleted")
+	}
+	if branch := getCurrentBranch(t, worktreeDir); branch != "feature-nostate" {
+		t.Errorf("worktree should be back on feature-nostate, got %s", branch)
+	}
+	if wt := worktreeAt(t, d, worktreeDir); wt.Locked {
+		t.Error("worktree should be unlocked")
+	}
+}
+
+func TestIntegration_DetachCheckoutFailure(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature-blocked")
+	worktreeDir := filepath.Join(resolvePath(t, t.TempDir()), "worktree-blocked")
+	createWorktree(t, repoDir, worktreeDir, "feature-blocked")
+	os.WriteFile(filepath.Join(worktreeDir, "blocker.txt"), []byte("tracked\n"), 0644)
+	runGit(t, worktreeDir, "add", "blocker.txt")
+	runGit(t, worktreeDir, "commit", "-m", "add blocker")
+
+	// An untracked file in the way of the checkout
+	os.WriteFile(filepath.Join(repoDir, "blocker.txt"), []byte("untracked\n"), 0644)
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+	result, err := d.Detach("feature-blocked", &Options{Yes: true, Force: true, To: repoDir})
+	if err == nil {
+		t.Fatal("Detach should fail when the checkout fails")
+	}
+	if result == nil || result.TempBranch != "feature-blocked__wt_detach" || result.CheckoutPath != "" {
+		t.Fatalf("expected the result of the detach, got %+v", result)
+	}
+	if branch := getCurrentBranch(t, worktreeDir); branch != "feature-blocked__wt_detach" {
+		t.Errorf("detach should stand, got %s", branch)
+	}
+	if branch := getCurrentBranch(t, repoDir); branch != "main" {
+		t.Errorf("target should stay on main, got %s", branch)
+	}
+
+	if _, err := d.Revert("feature-blocked", &Options{Yes: true}); err != nil {
+		t.Fatalf("Revert failed: %v", err)
+	}
+	if branch := getCurrentBranch(t, worktreeDir); branch != "feature-blocked" {
+		t.Errorf("worktree should be back on feature-blocked, got %s", branch)
+	}
+}
+
+func TestIntegration_KeepForeignLock(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature-foreign")
+	worktreeDir := filepath.Join(resolvePath(t, t.TempDir()), "worktree-foreign")
+	createWorktree(t, repoDir, worktreeDir, "feature-foreign")
+
+	cmd := exec.Command("git", "worktree", "lock", "--reason", "on usb drive", worktreeDir)
+	cmd.Dir = repoDir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("failed to lock worktree: %v\n%s", err, out)
+	}
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+
+	result, err := d.Detach("feature-foreign", &Options{Yes: true})
+	if err != nil {
+		t.Fatalf("Detach failed: %v", err)
+	}
+	if result.Locked {
+		t.Error("Detach should not lock an already locked worktree")
+	}
+
+	if _, err := d.Revert("feature-foreign", &Options{Yes: true}); err != nil {
+		t.Fatalf("Revert failed: %v", err)
+	}
+
+	// Verify: the existing lock should be left untouched
+	wt := worktreeAt(t, d, worktreeDir)
+	if !wt.Locked || wt.LockReason != "on usb drive" {
+		t.Errorf("existing lock should be kept, got locked=%v reason=%q", wt.Locked, wt.LockReason)
+	}
+}
+
+// runGit runs a git command in dir and fails the test on error
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("failed to run git %v: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// gitConfigValue returns a git config value, or empty string if unset
+func gitConfigValue(t *testing.T, dir, key string) string {
+	t.Helper()
+	cmd := exec.Command("git", "config", "--get", key)
+	cmd.Dir = dir
+	out, _ := cmd.Output()
+	return strings.TrimSpace(string(out))
+}
+
+func TestIntegration_TrackUpstream(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature-track")
+	runGit(t, repoDir, "config", "branch.feature-track.remote", "origin")
+	runGit(t, repoDir, "config", "branch.feature-track.merge", "refs/heads/feature-track")
+	worktreeDir := filepath.Join(resolvePath(t, t.TempDir()), "worktree-track")
+	createWorktree(t, repoDir, worktreeDir, "feature-track")
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+
+	result, err := d.Detach("feature-track", &Options{Yes: true, Track: true})
+	if err != nil {
+		t.Fatalf("Detach failed: %v", err)
+	}
+	if !result.Tracking {
+		t.Error("Detach should report that the upstream was copied")
+	}
+
+	// Verify: temp branch should track the same upstream with push disabled
+	if v := gitConfigValue(t, repoDir, "branch.feature-track__wt_detach.remote"); v != "origin" {
+		t.Errorf("remote: expected origin, got %q", v)
+	}
+	if v := gitConfigValue(t, repoDir, "branch.feature-track__wt_detach.merge"); v != "refs/heads/feature-track" {
+		t.Errorf("merge: expected refs/heads/feature-track, got %q", v)
+	}
+	if v := gitConfigValue(t, repoDir, "branch.feature-track__wt_detach.pushRemote"); v != DisabledPushRemote {
+		t.Errorf("pushRemote: expected %s, got %q", DisabledPushRemote, v)
+	}
+
+	if _, err := d.Revert("feature-track", &Options{Yes: true}); err != nil {
+		t.Fatalf("Revert failed: %v", err)
+	}
+
+	// Verify: config of the temp branch should be removed, the original kept
+	if v := gitConfigValue(t, repoDir, "branch.feature-track__wt_detach.remote"); v != "" {
+		t.Errorf("temp branch config should be removed, got remote %q", v)
+	}
+	if v := gitConfigValue(t, repoDir, "branch.feature-track.remote"); v != "origin" {
+		t.Errorf("original branch config should be kept, got remote %q", v)
+	}
+}
+
+func TestIntegration_NameTemplate(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature/tmpl")
+	worktreeDir := filepath.Join(resolvePath(t, t.TempDir()), "worktree-tmpl")
+	createWorktree(t, repoDir, worktreeDir, "feature/tmpl")
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+	if err := d.SetNameTemplate("wt-detach/{worktree}/{branch}"); err != nil {
+		t.Fatalf("SetNameTemplate failed: %v", err)
+	}
+
+	result, err := d.Detach("feature/tmpl", &Options{Yes: true})
+	if err != nil {
+		t.Fatalf("Detach failed: %v", err)
+	}
+	if result.TempBranch != "wt-detach/worktree-tmpl/feature/tmpl" {
+		t.Errorf("unexpected temp branch: %s", result.TempBranch)
+	}
+	if branch := getCurrentBranch(t, worktreeDir); branch != "wt-detach/worktree-tmpl/feature/tmpl" {
+		t.Errorf("worktree should be on the templated temp branch, got %s", branch)
+	}
+
+	// Test: Revert resolves the template in reverse
+	if _, err := d.Revert("feature/tmpl", &Options{Yes: true}); err != nil {
+		t.Fatalf("Revert failed: %v", err)
+	}
+	if branch := getCurrentBranch(t, worktreeDir); branch != "feature/tmpl" {
+		t.Errorf("worktree should be on feature/tmpl, got %s", branch)
+	}
+	if branchExistsInRepo(t, repoDir, "wt-detach/worktree-tmpl/feature/tmpl") {
+		t.Error("temp branch should be deleted")
+	}
+}
+
+func TestIntegration_NameTemplateInvalidRef(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature-invalid")
+	worktreeDir := filepath.Join(resolvePath(t, t.TempDir()), "worktree-invalid")
+	createWorktree(t, repoDir, worktreeDir, "feature-invalid")
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+	if err := d.SetNameTemplate("{branch}..lock"); err != nil {
+		t.Fatalf("SetNameTemplate failed: %v", err)
+	}
+
+	_, err := d.Detach("feature-invalid", &Options{Yes: true})
+	if err == nil {
+		t.Fatal("Detach should fail for an invalid temp branch name")
+	}
+	if !strings.Contains(err.Error(), "invalid temporary branch name") {
+		t.Errorf("error should mention invalid name: %v", err)
+	}
+	if branch := getCurrentBranch(t, worktreeDir); branch != "feature-invalid" {
+		t.Errorf("worktree should still be on feature-invalid, got %s", branch)
+	}
+}
+
+func TestIntegration_RevertAfterSuffixChange(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature-suffix")
+	worktreeDir := filepath.Join(resolvePath(t, t.TempDir()), "worktree-suffix")
+	createWorktree(t, repoDir, worktreeDir, "feature-suffix")
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+	if _, err := d.Detach("feature-suffix", &Options{Yes: true}); err != nil {
+		t.Fatalf("Detach failed: %v", err)
+	}
+
+	// Test: Revert finds the temp branch through recorded metadata
+	d = NewDetacher()
+	d.SetSuffix("__changed")
+	result, err := d.Revert("feature-suffix", &Options{Yes: true})
+	if err != nil {
+		t.Fatalf("Revert failed: %v", err)
+	}
+	if result.TempBranch != "feature-suffix__wt_detach" {
+		t.Errorf("unexpected temp branch: %s", result.TempBranch)
+	}
+	if branch := getCurrentBranch(t, worktreeDir); branch != "feature-suffix" {
+		t.Errorf("worktree should be on feature-suffix, got %s", branch)
+	}
+}
+
+func TestIntegration_RevertWithTempBranchOverride(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature-override")
+	createBranch(t, repoDir, "feature-override-manual")
+	createBranch(t, repoDir, "feature-override__old")
+	runGit(t, repoDir, "config", "branch.feature-override__old.wtDetachOrigin", "feature-override")
+	worktreeDir := filepath.Join(resolvePath(t, t.TempDir()), "worktree-override")
+	createWorktree(t, repoDir, worktreeDir, "feature-override__old")
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+
+	// Test: a branch that is not a temp branch is refused, and kept
+	_, err := d.Revert("feature-override", &Options{Yes: true, TempBranch: "feature-override-manual"})
+	if err == nil || !strings.Contains(err.Error(), "is not a temporary branch") {
+		t.Fatalf("Revert should refuse a branch that is not a temp branch, got %v", err)
+	}
+	if !branchExistsInRepo(t, repoDir, "feature-override-manual") {
+		t.Error("branch should not be deleted")
+	}
+
+	// Test: Revert switches the worktree on the given temp branch back
+	_, err = d.Revert("feature-override", &Options{Yes: true, TempBranch: "feature-override__old"})
+	if err != nil {
+		t.Fatalf("Revert failed: %v", err)
+	}
+	if branch := getCurrentBranch(t, worktreeDir); branch != "feature-override" {
+		t.Errorf("worktree should be on feature-override, got %s", branch)
+	}
+	if branchExistsInRepo(t, repoDir, "feature-override__old") {
+		t.Error("temp branch should be deleted")
+	}
+
+	// Test: a temp branch recorded for another branch is refused
+	createBranch(t, repoDir, "other")
+	createBranch(t, repoDir, "other__wt_detach")
+	runGit(t, repoDir, "config", "branch.other__wt_detach.wtDetachOrigin", "other")
+	_, err = d.Revert("feature-override", &Options{Yes: true, TempBranch: "other__wt_detach"})
+	if err == nil {
+		t.Fatal("Revert should fail for a temp branch of another branch")
+	}
+	if !strings.Contains(err.Error(), "was created for 'other'") {
+		t.Errorf("error should mention the recorded origin: %v", err)
+	}
+
+	// Test: so is one named for another branch, without metadata
+	createBranch(t, repoDir, "another__wt_detach")
+	if _, err = d.Revert("feature-override", &Options{Yes: true, TempBranch: "another__wt_detach"}); err == nil {
+		t.Fatal("Revert should fail for a temp branch named for another branch")
+	}
+	if !branchExistsInRepo(t, repoDir, "another__wt_detach") {
+		t.Error("temp branch of another branch should not be deleted")
+	}
+}
+
+func TestIntegration_RevertAfterCheckout(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature-co")
+	worktreeDir := filepath.Join(resolvePath(t, t.TempDir()), "worktree-co")
+	createWorktree(t, repoDir, worktreeDir, "feature-co")
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+
+	result, err := d.Detach("feature-co", &Options{Yes: true, Checkout: true})
+	if err != nil {
+		t.Fatalf("Detach failed: %v", err)
+	}
+	if result.CheckoutPath != repoDir {
+		t.Errorf("CheckoutPath: expected %s, got %s", repoDir, result.CheckoutPath)
+	}
+	if branch := getCurrentBranch(t, repoDir); branch != "feature-co" {
+		t.Fatalf("current worktree should be on feature-co, got %s", branch)
+	}
+
+	// Test: Revert fails while the current worktree is dirty
+	createUncommittedChange(t, repoDir)
+	if _, err := d.Revert("feature-co", &Options{Yes: true}); err == nil {
+		t.Fatal("Revert should fail with uncommitted changes in the current worktree")
+	}
+	os.Remove(filepath.Join(repoDir, "uncommitted.txt"))
+
+	// Test: Revert releases the branch from the current worktree first
+	result, err = d.Revert("feature-co", &Options{Yes: true})
+	if err != nil {
+		t.Fatalf("Revert failed: %v", err)
+	}
+	if result.ReleasedPath != repoDir || result.ReleasedTo != "main" {
+		t.Errorf("unexpected release: %s -> %s", result.ReleasedPath, result.ReleasedTo)
+	}
+	if branch := getCurrentBranch(t, repoDir); branch != "main" {
+		t.Errorf("current worktree should be back on main, got %s", branch)
+	}
+	if branch := getCurrentBranch(t, worktreeDir); branch != "feature-co" {
+		t.Errorf("worktree should be on feature-co, got %s", branch)
+	}
+	if branchExistsInRepo(t, repoDir, "feature-co__wt_detach") {
+		t.Error("temp branch should be deleted")
+	}
+}
+
+func TestIntegration_GiveAndRevert(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature-give")
+	createBranch(t, repoDir, "spare")
+	tempDir := resolvePath(t, t.TempDir())
+	giverDir := filepath.Join(tempDir, "worktree-giver")
+	recipientDir := filepath.Join(tempDir, "worktree-recipient")
+	createWorktree(t, repoDir, giverDir, "feature-give")
+	createWorktree(t, repoDir, recipientDir, "spare")
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(giverDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+
+	// Test: the branch must be checked out in the current worktree
+	if _, err := d.Detach("spare", &Options{Yes: true, Give: "worktree-recipient"}); err == nil {
+		t.Error("Give should fail for a branch not checked out here")
+	}
+
+	// Test: giving to the current worktree is rejected
+	if _, err := d.Detach("feature-give", &Options{Yes: true, Give: giverDir}); err == nil {
+		t.Error("Give should fail for the current worktree")
+	}
+
+	// Test: a dirty recipient is rejected
+	createUncommittedChange(t, recipientDir)
+	if _, err := d.Detach("feature-give", &Options{Yes: true, Give: "worktree-recipient"}); err == nil {
+		t.Error("Give should fail with uncommitted changes in the recipient")
+	}
+	os.Remove(filepath.Join(recipientDir, "uncommitted.txt"))
+	if branchExistsInRepo(t, repoDir, "feature-give__wt_detach") {
+		t.Fatal("temp branch should not be created when give fails")
+	}
+
+	result, err := d.Detach("feature-give", &Options{Yes: true, Give: "worktree-recipient"})
+	if err != nil {
+		t.Fatalf("Give failed: %v", err)
+	}
+	if result.WorktreePath != giverDir || result.CheckoutPath != recipientDir {
+		t.Errorf("unexpected paths: %s -> %s", result.WorktreePath, result.CheckoutPath)
+	}
+	if branch := getCurrentBranch(t, giverDir); branch != "feature-give__wt_detach" {
+		t.Errorf("giver should be on the temp branch, got %s", branch)
+	}
+	if branch := getCurrentBranch(t, recipientDir); branch != "feature-give" {
+		t.Errorf("recipient should be on feature-give, got %s", branch)
+	}
+	if wt := worktreeAt(t, d, giverDir); !wt.Locked {
+		t.Error("giver should be locked")
+	}
+
+	// Test: revert mirrors the give
+	result, err = d.Revert("feature-give", &Options{Yes: true})
+	if err != nil {
+		t.Fatalf("Revert failed: %v", err)
+	}
+	if result.ReleasedPath != recipientDir || result.ReleasedTo != "spare" {
+		t.Errorf("unexpected release: %s -> %s", result.ReleasedPath, result.ReleasedTo)
+	}
+	if branch := getCurrentBranch(t, giverDir); branch != "feature-give" {
+		t.Errorf("giver should be back on feature-give, got %s", branch)
+	}
+	if branch := getCurrentBranch(t, recipientDir); branch != "spare" {
+		t.Errorf("recipient should be back on spare, got %s", branch)
+	}
+	if wt := worktreeAt(t, d, giverDir); wt.Locked {
+		t.Error("giver should be unlocked")
+	}
+}
+
+func TestIntegration_DetachToWorktree(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature-to")
+	createBranch(t, repoDir, "runner")
+	tempDir := resolvePath(t, t.TempDir())
+	worktreeDir := filepath.Join(tempDir, "worktree-to")
+	runnerDir := filepath.Join(tempDir, "runner-1")
+	createWorktree(t, repoDir, worktreeDir, "feature-to")
+	createWorktree(t, repoDir, runnerDir, "runner")
+
+	// The current directory is not a worktree, like a supervisor process
+	oldWd, _ := os.Getwd()
+	os.Chdir(filepath.Join(repoDir, ".git"))
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+
+	// Test: the target cannot be the worktree the branch is checked out in
+	if _, err := d.Detach("feature-to", &Options{Yes: true, To: worktreeDir}); err == nil {
+		t.Error("Detach should fail when the target already has the branch")
+	}
+
+	// Test: a dirty target is rejected
+	createUncommittedChange(t, runnerDir)
+	if _, err := d.Detach("feature-to", &Options{Yes: true, To: "runner-*"}); err == nil {
+		t.Error("Detach should fail with uncommitted changes in the target")
+	}
+	os.Remove(filepath.Join(runnerDir, "uncommitted.txt"))
+
+	result, err := d.Detach("feature-to", &Options{Yes: true, To: "runner-*"})
+	if err != nil {
+		t.Fatalf("Detach failed: %v", err)
+	}
+	if result.CheckoutPath != runnerDir {
+		t.Errorf("CheckoutPath: expected %s, got %s", runnerDir, result.CheckoutPath)
+	}
+	if branch := getCurrentBranch(t, runnerDir); branch != "feature-to" {
+		t.Errorf("target should be on feature-to, got %s", branch)
+	}
+	if branch := getCurrentBranch(t, worktreeDir); branch != "feature-to__wt_detach" {
+		t.Errorf("worktree should be on the temp branch, got %s", branch)
+	}
+
+	// Test: revert releases the target first
+	result, err = d.Revert("feature-to", &Options{Yes: true})
+	if err != nil {
+		t.Fatalf("Revert failed: %v", err)
+	}
+	if result.ReleasedPath != runnerDir || result.ReleasedTo != "runner" {
+		t.Errorf("unexpected release: %s -> %s", result.ReleasedPath, result.ReleasedTo)
+	}
+	if branch := getCurrentBranch(t, worktreeDir); branch != "feature-to" {
+		t.Errorf("worktree should be back on feature-to, got %s", branch)
+	}
+	if branch := getCurrentBranch(t, runnerDir); branch != "runner" {
+		t.Errorf("target should be back on runner, got %s", branch)
+	}
+}
+
+func TestIntegration_DetachByWorktree(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature-wt")
+	tempDir := resolvePath(t, t.TempDir())
+	worktreeDir := filepath.Join(tempDir, "worktree-by-path")
+	detachedDir := filepath.Join(tempDir, "worktree-headless")
+	createWorktree(t, repoDir, worktreeDir, "feature-wt")
+	runGit(t, repoDir, "worktree", "add", "--detach", detachedDir)
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+
+	// Test: friendly errors for worktrees without a branch
+	if _, err := d.ResolveBranchWorktree("worktree-missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected not found error, got %v", err)
+	}
+	if _, err := d.ResolveBranchWorktree("worktree-headless"); err == nil || !strings.Contains(err.Error(), "detached") {
+		t.Errorf("expected detached HEAD error, got %v", err)
+	}
+
+	wt, err := d.ResolveBranchWorktree("worktree-by-path")
+	if err != nil {
+		t.Fatalf("ResolveBranchWorktree failed: %v", err)
+	}
+	if wt.Branch != "feature-wt" {
+		t.Fatalf("expected feature-wt, got %s", wt.Branch)
+	}
+
+	// Test: the branch must match the worktree
+	if _, err := d.Detach("main", &Options{Yes: true, Worktree: "worktree-by-path"}); err == nil {
+		t.Error("Detach should fail for a branch the worktree does not have")
+	}
+
+	if _, err := d.Detach(wt.Branch, &Options{Yes: true, Worktree: "worktree-by-path"}); err != nil {
+		t.Fatalf("Detach failed: %v", err)
+	}
+
+	// Test: revert by worktree finds the temp branch it is on
+	wt, err = d.ResolveBranchWorktree(worktreeDir)
+	if err != nil {
+		t.Fatalf("ResolveBranchWorktree failed: %v", err)
+	}
+	tmpBranch, origin, err := d.TempBranchOf(wt)
+	if err != nil {
+		t.Fatalf("TempBranchOf failed: %v", err)
+	}
+	if tmpBranch != "feature-wt__wt_detach" || origin != "feature-wt" {
+		t.Errorf("unexpected temp branch %q for origin %q", tmpBranch, origin)
+	}
+	if _, err := d.Revert(origin, &Options{Yes: true, TempBranch: tmpBranch}); err != nil {
+		t.Fatalf("Revert failed: %v", err)
+	}
+	if branch := getCurrentBranch(t, worktreeDir); branch != "feature-wt" {
+		t.Errorf("worktree should be back on feature-wt, got %s", branch)
+	}
+}
+
+func TestIntegration_RevertBranchCheckedOutElsewhere(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature-elsewhere")
+	worktreeDir := filepath.Join(resolvePath(t, t.TempDir()), "worktree-elsewhere")
+	createWorktree(t, repoDir, worktreeDir, "feature-elsewhere")
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+	if _, err := d.Detach("feature-elsewhere", &Options{Yes: true}); err != nil {
+		t.Fatalf("Detach failed: %v", err)
+	}
+
+	// Checked out manually, so revert must not switch it away
+	runGit(t, repoDir, "checkout", "feature-elsewhere")
+
+	_, err := d.Revert("feature-elsewhere", &Options{Yes: true})
+	if err == nil {
+		t.Fatal("Revert should fail while the branch is checked out elsewhere")
+	}
+	if !strings.Contains(err.Error(), repoDir) {
+		t.Errorf("error should mention where the branch is checked out: %v", err)
+	}
+	if branch := getCurrentBranch(t, repoDir); branch != "feature-elsewhere" {
+		t.Errorf("current worktree should be left on feature-elsewhere, got %s", branch)
+	}
+}
+
+func TestIntegration_RevertFromDetachedWorktree(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature-inside")
+	worktreeDir := filepath.Join(resolvePath(t, t.TempDir()), "worktree-inside")
+	createWorktree(t, repoDir, worktreeDir, "feature-inside")
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+	if _, err := d.Detach("feature-inside", &Options{Yes: true}); err != nil {
+		t.Fatalf("Detach failed: %v", err)
+	}
+
+	// Test: the original branch is derived from inside the detached worktree
+	os.Chdir(worktreeDir)
+	tmpBranch, origin, err := d.CurrentTempBranch()
+	if err != nil {
+		t.Fatalf("CurrentTempBranch failed: %v", err)
+	}
+	if tmpBranch != "feature-inside__wt_detach" || origin != "feature-inside" {
+		t.Errorf("unexpected temp branch %q for origin %q", tmpBranch, origin)
+	}
+
+	if _, err := d.Revert(origin, &Options{Yes: true, TempBranch: tmpBranch}); err != nil {
+		t.Fatalf("Revert failed: %v", err)
+	}
+	if branch := getCurrentBranch(t, worktreeDir); branch != "feature-inside" {
+		t.Errorf("worktree should be on feature-inside, got %s", branch)
+	}
+
+	// Test: a regular branch is not a temp branch
+	if _, _, err := d.CurrentTempBranch(); err == nil {
+		t.Error("CurrentTempBranch should fail on a regular branch")
+	}
+}
@@ -0,0 +1,121 @@
+package wtdetach
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FileChange is a file that changes between two commits
+type FileChange struct {
+	Status string // A, M, D or T as reported by git diff --name-status
+	Path   string
+}
+
+// RevertDiff describes how a worktree changes when it is switched from its
+// temporary branch back to the original branch
+type RevertDiff struct {
+	// Commits are the commits on the original branch that the temporary
+	// branch does not have, newest first
+	Commits []string
+	// TempOnly is the number of commits on the temporary branch that the
+	// original branch does not have, which are deleted with it
+	TempOnly int
+	// Stat summarizes the change, like "3 files changed, 10 insertions(+)"
+	Stat  string
+	Files []FileChange
+	// Blocking are untracked files in the worktree that the checkout would
+	// refuse to overwrite
+	Blocking []string
+	// Overwritten are ignored files in the worktree that the checkout would
+	// silently overwrite
+	Overwritten []string
+}
+
+// Empty reports whether the worktree does not change at all
+func (rd *RevertDiff) Empty() bool {
+	return len(rd.Commits) == 0 && rd.TempOnly == 0 && len(rd.Files) == 0
+}
+
+// DiffForRevert compares the temporary branch checked out in a worktree with
+// the original branch it will be switched back to
+func (d *Detacher) DiffForRevert(tmpBranch, branch, worktreePath string) (*RevertDiff, error) {
+	tmpRef, branchRef := "refs/heads/"+tmpBranch, "refs/heads/"+branch
+	rd := &RevertDiff{}
+
+	log, err := d.git.Run("log", "--oneline", "--no-decorate", tmpRef+".."+branchRef)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commits of '%s': %w", branch, err)
+	}
+	rd.Commits = splitLines(log)
+
+	tempOnly, err := d.git.Run("rev-list", tmpRef, "^"+branchRef)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commits of '%s': %w", tmpBranch, err)
+	}
+	rd.TempOnly = len(splitLines(tempOnly))
+
+	if rd.Stat, err = d.git.Run("diff", "--shortstat", tmpRef, branchRef); err != nil {
+		return nil, fmt.Errorf("failed to diff '%s' and '%s': %w", tmpBranch, branch, err)
+	}
+	nameStatus, err := d.git.Run("diff", "--name-status", "--no-renames", "-z", tmpRef, branchRef)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff '%s' and '%s': %w", tmpBranch, branch, err)
+	}
+	rd.Files = ParseNameStatus(nameStatus)
+
+	added := map[string]bool{}
+	for _, f := range rd.Files {
+		if f.Status == "A" {
+			added[f.Path] = true
+		}
+	}
+	if len(added) == 0 {
+		return rd, nil
+	}
+
+	untracked, err := d.git.RunInDir(worktreePath, "ls-files", "-z", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list untracked files in %s: %w", worktreePath, err)
+	}
+	for _, path := range strings.Split(untracked, "\x00") {
+		if added[path] {
+			rd.Blocking = append(rd.Blocking, path)
+		}
+	}
+
+	ignored, err := d.git.RunInDir(worktreePath, "ls-files", "-z", "--others", "--ignored", "--exclude-standard")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ignored files in %s: %w", worktreePath, err)
+	}
+	for _, path := range strings.Split(ignored, "\x00") {
+		if added[path] {
+			rd.Overwritten = append(rd.Overwritten, path)
+		}
+	}
+
+	return rd, nil
+}
+
+// ParseNameStatus parses the output of `git diff --name-status --no-renames -z`
+func ParseNameStatus(output string) []FileChange {
+	var files []FileChange
+	fields := strings.Split(output, "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		if fields[i] == "" {
+			break
+		}
+		files = append(files, FileChange{Status: fields[i], Path: fields[i+1]})
+	}
+	return files
+}
+
+// splitLines splits output into its non-empty lines
+func splitLines(output string) []string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,80 @@
+package wtdetach
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseNameStatus(t *testing.T) {
+	files := ParseNameStatus("M\x00README.md\x00A\x00dir/new file.txt\x00D\x00old.txt")
+	expected := []FileChange{
+		{Status: "M", Path: "README.md"},
+		{Status: "A", Path: "dir/new file.txt"},
+		{Status: "D", Path: "old.txt"},
+	}
+	if len(files) != len(expected) {
+		t.Fatalf("expected %d files, got %+v", len(expected), files)
+	}
+	for i, f := range files {
+		if f != expected[i] {
+			t.Errorf("file[%d]: expected %+v, got %+v", i, expected[i], f)
+		}
+	}
+	if files := ParseNameStatus(""); len(files) != 0 {
+		t.Errorf("expected no files, got %+v", files)
+	}
+}
+
+func TestIntegration_DiffForRevert(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	os.WriteFile(filepath.Join(repoDir, ".gitignore"), []byte("*.log\n"), 0644)
+	runGit(t, repoDir, "add", ".gitignore")
+	runGit(t, repoDir, "commit", "-m", "ignore logs")
+	createBranch(t, repoDir, "feature-diff")
+	worktreeDir := filepath.Join(resolvePath(t, t.TempDir()), "worktree-diff")
+	createWorktree(t, repoDir, worktreeDir, "feature-diff")
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+	if _, err := d.Detach("feature-diff", &Options{Yes: true, Checkout: true}); err != nil {
+		t.Fatalf("Detach failed: %v", err)
+	}
+
+	diff, err := d.DiffForRevert("feature-diff__wt_detach", "feature-diff", worktreeDir)
+	if err != nil {
+		t.Fatalf("DiffForRevert failed: %v", err)
+	}
+	if !diff.Empty() {
+		t.Errorf("expected no changes right after detaching, got %+v", diff)
+	}
+
+	// The borrowed branch gains files that already exist in the worktree
+	os.WriteFile(filepath.Join(repoDir, "new.txt"), []byte("new\n"), 0644)
+	os.WriteFile(filepath.Join(repoDir, "build.log"), []byte("log\n"), 0644)
+	runGit(t, repoDir, "add", "new.txt")
+	runGit(t, repoDir, "add", "-f", "build.log")
+	runGit(t, repoDir, "commit", "-m", "add files")
+	os.WriteFile(filepath.Join(worktreeDir, "new.txt"), []byte("local\n"), 0644)
+	os.WriteFile(filepath.Join(worktreeDir, "build.log"), []byte("local log\n"), 0644)
+
+	diff, err = d.DiffForRevert("feature-diff__wt_detach", "feature-diff", worktreeDir)
+	if err != nil {
+		t.Fatalf("DiffForRevert failed: %v", err)
+	}
+	if len(diff.Commits) != 1 || diff.TempOnly != 0 {
+		t.Errorf("expected 1 new commit, got %v and %d temp-only", diff.Commits, diff.TempOnly)
+	}
+	if len(diff.Files) != 2 || diff.Stat == "" {
+		t.Errorf("expected 2 changed files, got %+v (%s)", diff.Files, diff.Stat)
+	}
+	if len(diff.Blocking) != 1 || diff.Blocking[0] != "new.txt" {
+		t.Errorf("expected new.txt to block the checkout, got %v", diff.Blocking)
+	}
+	if len(diff.Overwritten) != 1 || diff.Overwritten[0] != "build.log" {
+		t.Errorf("expected build.log to be overwritten, got %v", diff.Overwritten)
+	}
+}
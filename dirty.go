@@ -0,0 +1,122 @@
+package wtdetach
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// UncommittedFile is a file with uncommitted changes in a worktree
+type UncommittedFile struct {
+	Status string // two letters as shown by git status --short, like " M", "A " or "??"
+	Path   string
+}
+
+// ParseStatus parses the output of `git status --porcelain=v2 -z`, which
+// unlike the v1 format survives the trimming of the output
+func ParseStatus(output string) []UncommittedFile {
+	var files []UncommittedFile
+	entries := strings.Split(output, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 2 {
+			continue
+		}
+		var fields []string
+		switch entry[0] {
+		case '1':
+			fields = strings.SplitN(entry, " ", 9)
+		case '2':
+			fields = strings.SplitN(entry, " ", 10)
+			i++ // the original path of a rename follows
+		case 'u':
+			fields = strings.SplitN(entry, " ", 11)
+		case '?':
+			files = append(files, UncommittedFile{Status: "??", Path: entry[2:]})
+			continue
+		default:
+			continue
+		}
+		if len(fields) < 2 {
+			continue
+		}
+		status := strings.ReplaceAll(fields[1], ".", " ")
+		files = append(files, UncommittedFile{Status: status, Path: fields[len(fields)-1]})
+	}
+	return files
+}
+
+// UncommittedStatus returns the files with uncommitted changes in a worktree,
+// along with their status
+func (d *Detacher) UncommittedStatus(worktreePath string) []UncommittedFile {
+	output, err := d.git.RunInDir(worktreePath, "status", "--porcelain=v2", "-z")
+	if err != nil {
+		return nil
+	}
+	return ParseStatus(output)
+}
+
+// ShowDiff shows the uncommitted changes to tracked files in a worktree on
+// the terminal, through the pager
+func (d *Detacher) ShowDiff(worktreePath string) error {
+	return d.git.RunAttached(worktreePath, "diff", "HEAD")
+}
+
+// Stash is a stash entry holding the changes set aside in a worktree
+type Stash struct {
+	Path   string
+	Commit string
+}
+
+// StashChanges stashes the uncommitted changes in a worktree, untracked files
+// included. It returns nil if there is nothing to stash.
+func (d *Detacher) StashChanges(worktreePath, message string) (*Stash, error) {
+	before, _ := d.git.Run("rev-parse", "--verify", "--quiet", "refs/stash")
+	if _, err := d.git.RunInDir(worktreePath, "stash", "push", "--include-untracked", "--quiet", "-m", message); err != nil {
+		return nil, fmt.Errorf("failed to stash changes in %s: %w", worktreePath, err)
+	}
+	after, _ := d.git.Run("rev-parse", "--verify", "--quiet", "refs/stash")
+	if after == "" || after == before {
+		return nil, nil
+	}
+	return &Stash{Path: worktreePath, Commit: after}, nil
+}
+
+// RestoreStash applies a stash to the worktree it was taken in, staged
+// changes staged, and drops it. If it does not apply, the stash is kept.
+func (d *Detacher) RestoreStash(s *Stash) error {
+	entry, err := d.stashEntry(s.Commit)
+	if err != nil {
+		return err
+	}
+	if _, err := d.git.RunInDir(s.Path, "stash", "pop", "--index", "--quiet", entry); err != nil {
+		return fmt.Errorf("failed to restore stashed changes in %s; they are kept in %s (%s)", s.Path, entry, shortHash(s.Commit))
+	}
+	return nil
+}
+
+// stashEntry returns the stash@{n} name of a stash commit
+func (d *Detacher) stashEntry(commit string) (string, error) {
+	list, err := d.git.Run("stash", "list", "--format=%H")
+	if err != nil {
+		return "", fmt.Errorf("failed to list stashes: %w", err)
+	}
+	for i, hash := range splitLines(list) {
+		if hash == commit {
+			return "stash@{" + strconv.Itoa(i) + "}", nil
+		}
+	}
+	return "", fmt.Errorf("stash %s no longer exists", shortHash(commit))
+}
+
+// CommitWIP commits all uncommitted changes in a worktree, untracked files
+// included, skipping hooks. It returns the new commit.
+func (d *Detacher) CommitWIP(worktreePath, message string) (string, error) {
+	if _, err := d.git.RunInDir(worktreePath, "add", "--all"); err != nil {
+		return "", fmt.Errorf("failed to stage changes in %s: %w", worktreePath, err)
+	}
+	if _, err := d.git.RunInDir(worktreePath, "commit", "--quiet", "--no-verify", "-m", message); err != nil {
+		return "", fmt.Errorf("failed to commit changes in %s: %w", worktreePath, err)
+	}
+	return d.git.RunInDir(worktreePath, "rev-parse", "HEAD")
+}
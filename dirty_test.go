@@ -0,0 +1,110 @@
+package wtdetach
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseStatus(t *testing.T) {
+	output := "1 .M N... 100644 100644 100644 aaa aaa README.md\x00" +
+		"1 A. N... 000000 100644 100644 000 bbb dir/new file.txt\x00" +
+		"2 R. N... 100644 100644 100644 ccc ccc R100 renamed.txt\x00old.txt\x00" +
+		"u UU N... 100644 100644 100644 100644 ddd eee fff conflict.txt\x00" +
+		"? untracked.txt\x00"
+
+	want := []UncommittedFile{
+		{Status: " M", Path: "README.md"},
+		{Status: "A ", Path: "dir/new file.txt"},
+		{Status: "R ", Path: "renamed.txt"},
+		{Status: "UU", Path: "conflict.txt"},
+		{Status: "??", Path: "untracked.txt"},
+	}
+	if got := ParseStatus(output); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseStatus() = %+v, want %+v", got, want)
+	}
+	if got := ParseStatus(""); got != nil {
+		t.Errorf("ParseStatus(\"\") = %+v, want nil", got)
+	}
+}
+
+func TestIntegration_StashAndRestore(t *testing.T) {
+	repoDir := setupTestRepo(t)
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+
+	// Test: nothing to stash
+	s, err := d.StashChanges(repoDir, "nothing")
+	if err != nil || s != nil {
+		t.Fatalf("expected no stash, got %+v, %v", s, err)
+	}
+
+	readme := filepath.Join(repoDir, "README.md")
+	os.WriteFile(readme, []byte("# Test\nstaged\n"), 0644)
+	runGit(t, repoDir, "add", "README.md")
+	createUncommittedChange(t, repoDir)
+
+	want := []UncommittedFile{
+		{Status: "M ", Path: "README.md"},
+		{Status: "??", Path: "uncommitted.txt"},
+	}
+	if got := d.UncommittedStatus(repoDir); !reflect.DeepEqual(got, want) {
+		t.Errorf("UncommittedStatus() = %+v, want %+v", got, want)
+	}
+
+	s, err = d.StashChanges(repoDir, "set aside")
+	if err != nil || s == nil {
+		t.Fatalf("StashChanges failed: %+v, %v", s, err)
+	}
+	if d.HasUncommittedChanges(repoDir) {
+		t.Error("worktree should be clean after stashing, untracked files included")
+	}
+
+	// Another stash on top must not get in the way
+	os.WriteFile(readme, []byte("# Other\n"), 0644)
+	runGit(t, repoDir, "stash", "push", "-m", "other")
+
+	if err := d.RestoreStash(s); err != nil {
+		t.Fatalf("RestoreStash failed: %v", err)
+	}
+	if got := d.UncommittedStatus(repoDir); !reflect.DeepEqual(got, want) {
+		t.Errorf("restored status = %+v, want %+v", got, want)
+	}
+	if list := runGit(t, repoDir, "stash", "list", "--format=%s"); list != "On main: other" {
+		t.Errorf("only the other stash should be left, got %q", list)
+	}
+
+	// Test: a stash that is gone
+	if err := d.RestoreStash(s); err == nil {
+		t.Error("RestoreStash should fail for a dropped stash")
+	}
+}
+
+func TestIntegration_CommitWIP(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createUncommittedChange(t, repoDir)
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+	commit, err := d.CommitWIP(repoDir, "WIP: test")
+	if err != nil {
+		t.Fatalf("CommitWIP failed: %v", err)
+	}
+	if head := runGit(t, repoDir, "rev-parse", "HEAD"); head != commit {
+		t.Errorf("CommitWIP returned %s, HEAD is %s", commit, head)
+	}
+	if d.HasUncommittedChanges(repoDir) {
+		t.Error("worktree should be clean after a WIP commit")
+	}
+	if files := runGit(t, repoDir, "show", "--name-only", "--format=", "HEAD"); files != "uncommitted.txt" {
+		t.Errorf("WIP commit should include untracked files, got %q", files)
+	}
+}
@@ -0,0 +1,250 @@
+package wtdetach
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// staleLockAge is how old an index.lock must be to be considered stale
+const staleLockAge = 10 * time.Minute
+
+// Problem is an inconsistency found by Diagnose
+type Problem struct {
+	Description string
+	Fix         string // describes the fix, empty if it must be fixed by hand
+	// NeedsForce is set when the fix may lose work and is only applied with
+	// --force
+	NeedsForce bool
+	Fixed      bool
+
+	repair func() error
+}
+
+// Diagnose checks all worktrees and temporary branches against each other.
+// Fixes that may lose commits are only offered with force.
+func (d *Detacher) Diagnose(force bool) ([]*Problem, error) {
+	worktrees, err := d.ListWorktrees()
+	if err != nil {
+		return nil, err
+	}
+	states, err := d.ListTempBranches()
+	if err != nil {
+		return nil, err
+	}
+	temps := make(map[string]*State, len(states))
+	for _, st := range states {
+		temps[st.TempBranch] = st
+	}
+
+	var problems []*Problem
+	problems = append(problems, d.diagnoseWorktrees(worktrees, temps)...)
+
+	expired, err := d.FindExpiredDetaches(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range expired {
+		// Expired temp branches that are not checked out are reported as orphans
+		if e.Worktree != nil {
+			problems = append(problems, d.diagnoseExpired(e, force))
+		}
+	}
+
+	orphans, err := d.FindOrphanedTempBranches()
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range orphans {
+		problems = append(problems, d.diagnoseOrphan(o, force))
+	}
+
+	return problems, nil
+}
+
+// Repair applies the fixes of the given problems
+func (d *Detacher) Repair(problems []*Problem) error {
+	var errs []error
+	for _, p := range problems {
+		if p.repair == nil || p.Fixed || p.NeedsForce {
+			continue
+		}
+		if err := p.repair(); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		p.Fixed = true
+	}
+	return errors.Join(errs...)
+}
+
+func (d *Detacher) diagnoseWorktrees(worktrees []Worktree, temps map[string]*State) []*Problem {
+	var problems []*Problem
+
+	holders := map[string][]string{}
+	for _, wt := range worktrees {
+		if wt.Branch != "" {
+			holders[wt.Branch] = append(holders[wt.Branch], wt.Path)
+		}
+
+		if wt.Prunable || !dirExists(wt.Path) {
+			if IsOwnLock(&wt) {
+				// Reported with its orphaned temp branch
+				continue
+			}
+			p := &Problem{
+				Description: fmt.Sprintf("Worktree %s no longer exists on disk but is still registered.", wt.Path),
+			}
+			if wt.Locked {
+				p.Description += fmt.Sprintf(" It is locked%s, so it may be on a drive that is not mounted.", lockNote(&wt))
+			} else {
+				p.Fix = "prune the worktree registration (git worktree prune)"
+				p.repair = d.PruneWorktrees
+			}
+			problems = append(problems, p)
+			continue
+		}
+
+		if lock := d.indexLockPath(wt.Path); lock != "" {
+			problems = append(problems, &Problem{
+				Description: fmt.Sprintf("Worktree %s has a stale index.lock, left behind by a git process that did not finish.", wt.Path),
+				Fix:         fmt.Sprintf("remove %s", lock),
+				repair: func() error {
+					return os.Remove(lock)
+				},
+			})
+		}
+
+		st, onTemp := temps[wt.Branch]
+		if IsOwnLock(&wt) && !onTemp {
+			problems = append(problems, &Problem{
+				Description: fmt.Sprintf("Worktree %s is still locked by wt-detach%s, but it is not on a temp branch.", wt.Path, lockNote(&wt)),
+				Fix:         "unlock the worktree",
+				repair: func() error {
+					return d.UnlockWorktree(wt.Path)
+				},
+			})
+		}
+
+		if onTemp && st.Origin != "" && !d.BranchExists(st.Origin) {
+			problems = append(problems, &Problem{
+				Description: fmt.Sprintf("Worktree %s is on temp branch '%s', but its original branch '%s' no longer exists.", wt.Path, st.TempBranch, st.Origin),
+				Fix:         fmt.Sprintf("rename '%s' back to '%s'", st.TempBranch, st.Origin),
+				repair: func() error {
+					if err := d.restoreOrigin(st); err != nil {
+						return err
+					}
+					if IsOwnLock(&wt) {
+						return d.UnlockWorktree(wt.Path)
+					}
+					return nil
+				},
+			})
+		}
+	}
+
+	branches := make([]string, 0, len(holders))
+	for branch := range holders {
+		branches = append(branches, branch)
+	}
+	sort.Strings(branches)
+	for _, branch := range branches {
+		paths := holders[branch]
+		if len(paths) < 2 {
+			continue
+		}
+		desc := fmt.Sprintf("Branch '%s' is checked out in %d worktrees at once: %s.", branch, len(paths), strings.Join(paths, ", "))
+		for _, st := range temps {
+			if st.Origin == branch {
+				desc += fmt.Sprintf(" It was detached to '%s', so revert cannot tell which worktree to restore.", st.TempBranch)
+				break
+			}
+		}
+		problems = append(problems, &Problem{
+			Description: desc + " Switch all but one of them to another branch.",
+		})
+	}
+
+	return problems
+}
+
+func (d *Detacher) diagnoseExpired(e *ExpiredDetach, force bool) *Problem {
+	p := &Problem{
+		Description: fmt.Sprintf("Detach of '%s' to '%s' in %s expired on %s.", e.Origin, e.TempBranch, e.Worktree.Path, formatTime(e.Expires)),
+	}
+	if len(e.DirtyWorktrees) > 0 {
+		p.Description += fmt.Sprintf(" Uncommitted changes in: %s.", strings.Join(e.DirtyWorktrees, ", "))
+		p.NeedsForce = !force
+	}
+
+	p.Fix = fmt.Sprintf("revert '%s'", e.Origin)
+	p.repair = func() error {
+		return d.ExpireDetaches([]*ExpiredDetach{e}, &Options{Force: force})
+	}
+	return p
+}
+
+func (d *Detacher) diagnoseOrphan(o *OrphanedTempBranch, force bool) *Problem {
+	p := &Problem{
+		Description: fmt.Sprintf("Temp branch '%s' is %s", o.TempBranch, o.Reason),
+	}
+
+	switch {
+	case o.Status == TempOriginDeleted && o.missingWorktree == nil:
+		p.Description += fmt.Sprintf(", and its original branch '%s' no longer exists.", o.Origin)
+		p.Fix = fmt.Sprintf("rename '%s' back to '%s'", o.TempBranch, o.Origin)
+		p.repair = func() error {
+			if _, err := d.unlockIfOwned(o.State); err != nil {
+				return err
+			}
+			return d.restoreOrigin(o.State)
+		}
+		return p
+	case o.Safe():
+		p.Description += fmt.Sprintf(" (%s).", o.Status)
+	default:
+		p.Description += fmt.Sprintf(" (%s). Deleting it may lose commits.", o.Status)
+		p.NeedsForce = !force
+	}
+
+	p.Fix = fmt.Sprintf("delete '%s'", o.TempBranch)
+	p.repair = func() error {
+		return d.DeleteOrphans([]*OrphanedTempBranch{o})
+	}
+	return p
+}
+
+// restoreOrigin renames a temp branch back to its deleted original branch
+// and removes the wt-detach metadata from it
+func (d *Detacher) restoreOrigin(st *State) error {
+	if st.Origin == "" {
+		return fmt.Errorf("original branch of '%s' is unknown", st.TempBranch)
+	}
+	if _, err := d.git.Run("branch", "-m", st.TempBranch, st.Origin); err != nil {
+		return fmt.Errorf("failed to rename '%s' to '%s': %w", st.TempBranch, st.Origin, err)
+	}
+	d.DeleteSnapshot(SnapshotRef(st.TempBranch))
+	d.ClearState(st.Origin)
+	return nil
+}
+
+// indexLockPath returns the path of a stale index.lock in a worktree, if any
+func (d *Detacher) indexLockPath(worktreePath string) string {
+	path, err := d.git.RunInDir(worktreePath, "rev-parse", "--git-path", "index.lock")
+	if err != nil {
+		return ""
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(worktreePath, path)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) < staleLockAge {
+		return ""
+	}
+	return path
+}
@@ -0,0 +1,190 @@
+package wtdetach
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// findProblem returns the first problem whose description contains substr
+func findProblem(problems []*Problem, substr string) *Problem {
+	for _, p := range problems {
+		if strings.Contains(p.Description, substr) {
+			return p
+		}
+	}
+	return nil
+}
+
+func TestIntegration_DoctorHealthy(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature-ok")
+	worktreeDir := filepath.Join(resolvePath(t, t.TempDir()), "worktree-ok")
+	createWorktree(t, repoDir, worktreeDir, "feature-ok")
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+	if _, err := d.Detach("feature-ok", &Options{Yes: true}); err != nil {
+		t.Fatalf("Detach failed: %v", err)
+	}
+
+	problems, err := d.Diagnose(false)
+	if err != nil {
+		t.Fatalf("Diagnose failed: %v", err)
+	}
+	if len(problems) != 0 {
+		t.Errorf("expected no problems, got %d: %s", len(problems), problems[0].Description)
+	}
+}
+
+func TestIntegration_DoctorOriginDeleted(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature-deleted")
+	worktreeDir := filepath.Join(resolvePath(t, t.TempDir()), "worktree-deleted")
+	createWorktree(t, repoDir, worktreeDir, "feature-deleted")
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+	if _, err := d.Detach("feature-deleted", &Options{Yes: true}); err != nil {
+		t.Fatalf("Detach failed: %v", err)
+	}
+	runGit(t, repoDir, "branch", "-D", "feature-deleted")
+
+	problems, err := d.Diagnose(false)
+	if err != nil {
+		t.Fatalf("Diagnose failed: %v", err)
+	}
+	p := findProblem(problems, "no longer exists")
+	if p == nil {
+		t.Fatalf("expected a problem about the deleted original, got %d problems", len(problems))
+	}
+	if p.Fix == "" {
+		t.Fatal("problem should offer a fix")
+	}
+
+	// Test: the fix renames the temp branch back to the original
+	if err := d.Repair(problems); err != nil {
+		t.Fatalf("Repair failed: %v", err)
+	}
+	if branch := getCurrentBranch(t, worktreeDir); branch != "feature-deleted" {
+		t.Errorf("worktree should be on feature-deleted, got %s", branch)
+	}
+	if v := gitConfigValue(t, repoDir, "branch.feature-deleted.wtDetachOrigin"); v != "" {
+		t.Errorf("metadata should be removed from the restored branch, got %q", v)
+	}
+	if wt := worktreeAt(t, d, worktreeDir); wt.Locked {
+		t.Error("worktree should be unlocked")
+	}
+
+	problems, err = d.Diagnose(false)
+	if err != nil {
+		t.Fatalf("Diagnose failed: %v", err)
+	}
+	if len(problems) != 0 {
+		t.Errorf("expected no problems after repair, got %d: %s", len(problems), problems[0].Description)
+	}
+}
+
+func TestIntegration_DoctorStaleLocks(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature-stale")
+	worktreeDir := filepath.Join(resolvePath(t, t.TempDir()), "worktree-stale")
+	createWorktree(t, repoDir, worktreeDir, "feature-stale")
+
+	// A wt-detach lock left behind on a worktree that is not detached
+	runGit(t, repoDir, "worktree", "lock", "--reason", LockReason("feature-stale"), worktreeDir)
+
+	// An index.lock left behind by a crashed git process
+	lock := filepath.Join(repoDir, ".git", "index.lock")
+	if err := os.WriteFile(lock, nil, 0644); err != nil {
+		t.Fatalf("failed to create index.lock: %v", err)
+	}
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(lock, old, old)
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+	problems, err := d.Diagnose(false)
+	if err != nil {
+		t.Fatalf("Diagnose failed: %v", err)
+	}
+	if findProblem(problems, "stale index.lock") == nil {
+		t.Error("expected a problem about the stale index.lock")
+	}
+	if findProblem(problems, "still locked by wt-detach") == nil {
+		t.Error("expected a problem about the stale worktree lock")
+	}
+
+	if err := d.Repair(problems); err != nil {
+		t.Fatalf("Repair failed: %v", err)
+	}
+	if _, err := os.Stat(lock); !os.IsNotExist(err) {
+		t.Error("index.lock should be removed")
+	}
+	if wt := worktreeAt(t, d, worktreeDir); wt.Locked {
+		t.Error("worktree should be unlocked")
+	}
+}
+
+func TestIntegration_DoctorNeedsForce(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature-unsafe")
+	worktreeDir := filepath.Join(resolvePath(t, t.TempDir()), "worktree-unsafe")
+	createWorktree(t, repoDir, worktreeDir, "feature-unsafe")
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+	if _, err := d.Detach("feature-unsafe", &Options{Yes: true}); err != nil {
+		t.Fatalf("Detach failed: %v", err)
+	}
+
+	// The temp branch gets a commit of its own, then is left behind
+	runGit(t, worktreeDir, "commit", "--allow-empty", "-m", "extra")
+	runGit(t, worktreeDir, "checkout", "--detach")
+
+	problems, err := d.Diagnose(false)
+	if err != nil {
+		t.Fatalf("Diagnose failed: %v", err)
+	}
+	p := findProblem(problems, "may lose commits")
+	if p == nil {
+		t.Fatal("expected a problem about the temp branch with extra commits")
+	}
+	if !p.NeedsForce || p.Fix != "delete 'feature-unsafe__wt_detach'" {
+		t.Errorf("fix should need --force: %+v", p)
+	}
+	if err := d.Repair(problems); err != nil {
+		t.Fatalf("Repair failed: %v", err)
+	}
+	if p.Fixed || !branchExistsInRepo(t, repoDir, "feature-unsafe__wt_detach") {
+		t.Error("temp branch should be kept without --force")
+	}
+
+	// Test: with --force the fix is applied
+	if problems, err = d.Diagnose(true); err != nil {
+		t.Fatalf("Diagnose failed: %v", err)
+	}
+	if p = findProblem(problems, "may lose commits"); p == nil || p.NeedsForce {
+		t.Fatalf("fix should not need --force with --force: %+v", p)
+	}
+	if err := d.Repair(problems); err != nil {
+		t.Fatalf("Repair failed: %v", err)
+	}
+	if branchExistsInRepo(t, repoDir, "feature-unsafe__wt_detach") {
+		t.Error("temp branch should be deleted with --force")
+	}
+}
@@ -0,0 +1,199 @@
+package wtdetach
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+)
+
+// ExitError makes the process exit with Code without printing anything more,
+// for example to pass on the exit code of a command run by exec
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// Borrowed is a branch checked out in the current worktree for the duration
+// of a command
+type Borrowed struct {
+	Branch      string
+	CurrentPath string
+	// TempBranch is the temporary branch of the worktree the branch was
+	// detached from, empty if it was not checked out in another worktree
+	TempBranch string
+	// WorktreePath is the worktree the branch was detached from
+	WorktreePath string
+	// Switched is set if the current worktree was switched without a detach
+	Switched   bool
+	PrevBranch string
+	PrevHead   string
+}
+
+// Borrow checks out a branch in the current worktree, detaching it from
+// another worktree first if needed. Return undoes it.
+func (d *Detacher) Borrow(branch string, opts *Options) (*Borrowed, error) {
+	if !d.BranchExists(branch) {
+		return nil, fmt.Errorf("branch '%s' does not exist", branch)
+	}
+
+	currentPath, err := d.GetCurrentWorktreePath()
+	if err != nil {
+		return nil, err
+	}
+	b := &Borrowed{Branch: branch, CurrentPath: currentPath}
+
+	wt, err := d.FindWorktreeForBranch(branch)
+	if err != nil {
+		return nil, err
+	}
+	if wt != nil {
+		detachOpts := *opts
+		detachOpts.Checkout = true
+		result, err := d.Detach(branch, &detachOpts)
+		if err != nil {
+			// The command cannot run without the branch
+			if result != nil {
+				d.Revert(branch, &Options{Yes: true, Force: opts.Force, TempBranch: result.TempBranch})
+			}
+			return nil, err
+		}
+		b.TempBranch = result.TempBranch
+		b.WorktreePath = result.WorktreePath
+		return b, nil
+	}
+
+	prevBranch, prevHead, err := d.CurrentHead(currentPath)
+	if err != nil {
+		return nil, err
+	}
+	if prevBranch == branch {
+		return b, nil
+	}
+	if opts.DryRun {
+		return b, nil
+	}
+	if err := d.Checkout(currentPath, branch); err != nil {
+		return nil, err
+	}
+	b.Switched = true
+	b.PrevBranch = prevBranch
+	b.PrevHead = prevHead
+	return b, nil
+}
+
+// Return reverts a borrowed branch, switching both worktrees back. Changes
+// left by the command do not stop it.
+func (d *Detacher) Return(b *Borrowed) error {
+	switch {
+	case b.TempBranch != "":
+		result, err := d.Revert(b.Branch, &Options{Yes: true, Force: true, TempBranch: b.TempBranch})
+		if err != nil {
+			return err
+		}
+		if result.Conflict != nil {
+			return result.Conflict
+		}
+		return nil
+	case b.Switched && b.PrevBranch != "":
+		return d.Checkout(b.CurrentPath, b.PrevBranch)
+	case b.Switched:
+		return d.CheckoutDetached(b.CurrentPath, b.PrevHead)
+	}
+	return nil
+}
+
+// ExecResult is the outcome of Exec
+type ExecResult struct {
+	*Borrowed
+	// Code is the exit code of the command
+	Code int
+	// RunErr is set if the command could not be started
+	RunErr error
+	// ReturnErr is set if the branch could not be returned
+	ReturnErr error
+}
+
+// Exec borrows a branch, runs a command on it, and returns the branch even
+// if the command fails or a signal arrives. SIGINT, SIGTERM and SIGHUP do
+// not stop the process while the branch is borrowed.
+func (d *Detacher) Exec(branch string, args []string, opts *Options) (*ExecResult, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("no command given")
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(sigs)
+
+	b, err := d.Borrow(branch, opts)
+	if err != nil {
+		return nil, err
+	}
+	result := &ExecResult{Borrowed: b}
+	if opts.DryRun {
+		return result, nil
+	}
+
+	select {
+	case sig := <-sigs:
+		// Interrupted while borrowing, so the command is not started
+		result.Code = 128 + int(sig.(syscall.Signal))
+	default:
+		result.Code, result.RunErr = runCommand(args, sigs)
+	}
+
+	if err := d.Return(b); err != nil {
+		result.ReturnErr = fmt.Errorf("failed to revert '%s': %w\n  Run: git wt-detach --revert %s", branch, err, branch)
+	}
+	return result, nil
+}
+
+// runCommand runs a command in the current directory with the standard
+// streams attached and returns its exit code. SIGTERM and SIGHUP are passed
+// on to the command. SIGINT is passed on as well unless stdin is a terminal,
+// where Ctrl-C already reaches the command through its process group.
+func runCommand(args []string, sigs <-chan os.Signal) (int, error) {
+	forwardInterrupt := !isTerminal(os.Stdin)
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return 127, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-sigs:
+				if sig != os.Interrupt || forwardInterrupt {
+					cmd.Process.Signal(sig)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	err := cmd.Wait()
+	close(done)
+
+	if err == nil {
+		return 0, nil
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return 1, err
+	}
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal()), nil
+	}
+	return exitErr.ExitCode(), nil
+}
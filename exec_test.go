@@ -0,0 +1,86 @@
+package wtdetach
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIntegration_Exec(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature-exec")
+	createBranch(t, repoDir, "feature-free")
+	worktreeDir := filepath.Join(resolvePath(t, t.TempDir()), "worktree-exec")
+	createWorktree(t, repoDir, worktreeDir, "feature-exec")
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+
+	// Test: the command runs on the borrowed branch and its exit code is kept
+	marker := filepath.Join(t.TempDir(), "branch")
+	result, err := d.Exec("feature-exec", []string{"sh", "-c", "git branch --show-current > " + marker + "; exit 3"}, &Options{Yes: true})
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if result.Code != 3 {
+		t.Errorf("expected exit code 3, got %d", result.Code)
+	}
+	if result.ReturnErr != nil {
+		t.Errorf("unexpected revert error: %v", result.ReturnErr)
+	}
+	if data, _ := os.ReadFile(marker); string(data) != "feature-exec\n" {
+		t.Errorf("command should run on feature-exec, got %q", data)
+	}
+	if branch := getCurrentBranch(t, repoDir); branch != "main" {
+		t.Errorf("current worktree should be back on main, got %s", branch)
+	}
+	if branch := getCurrentBranch(t, worktreeDir); branch != "feature-exec" {
+		t.Errorf("worktree should be back on feature-exec, got %s", branch)
+	}
+	if branchExistsInRepo(t, repoDir, "feature-exec__wt_detach") {
+		t.Error("temp branch should be deleted")
+	}
+
+	// Test: a branch not checked out elsewhere is switched to and back
+	result, err = d.Exec("feature-free", []string{"true"}, &Options{Yes: true})
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if result.Code != 0 || !result.Switched || result.TempBranch != "" {
+		t.Errorf("unexpected result: %+v", result.Borrowed)
+	}
+	if branch := getCurrentBranch(t, repoDir); branch != "main" {
+		t.Errorf("current worktree should be back on main, got %s", branch)
+	}
+
+	// Test: a missing command still reverts
+	result, err = d.Exec("feature-exec", []string{"wt-detach-no-such-command"}, &Options{Yes: true})
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if result.RunErr == nil || result.Code != 127 {
+		t.Errorf("expected a run error with code 127, got %d, %v", result.Code, result.RunErr)
+	}
+	if branch := getCurrentBranch(t, worktreeDir); branch != "feature-exec" {
+		t.Errorf("worktree should be back on feature-exec, got %s", branch)
+	}
+}
+
+func TestRunCommandForwardsInterrupt(t *testing.T) {
+	if isTerminal(os.Stdin) {
+		t.Skip("SIGINT reaches the command from the terminal")
+	}
+
+	sigs := make(chan os.Signal, 1)
+	sigs <- os.Interrupt
+	code, err := runCommand([]string{"sleep", "10"}, sigs)
+	if err != nil {
+		t.Fatalf("runCommand failed: %v", err)
+	}
+	if code != 130 {
+		t.Errorf("expected exit code 130, got %d", code)
+	}
+}
@@ -0,0 +1,151 @@
+package wtdetach
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// deadlineLayouts are the layouts accepted for --until, in local time unless
+// they include a zone
+var deadlineLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+}
+
+// ParseExpiry parses a duration such as 2h, 90m or 3d (days are not
+// supported by time.ParseDuration)
+func ParseExpiry(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid duration '%s'", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	dur, err := time.ParseDuration(s)
+	if err != nil || dur <= 0 {
+		return 0, fmt.Errorf("invalid duration '%s' (e.g. 30m, 2h, 3d)", s)
+	}
+	return dur, nil
+}
+
+// ParseDeadline parses a point in time such as 2026-01-31 18:00, an RFC 3339
+// timestamp, a date (2026-01-31), which means the end of that day, or a time
+// of day (18:00), which means the next time that time comes around
+func ParseDeadline(s string, now time.Time) (time.Time, error) {
+	if clock, err := time.ParseInLocation("15:04", s, now.Location()); err == nil {
+		deadline := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+		if !deadline.After(now) {
+			deadline = deadline.AddDate(0, 0, 1)
+		}
+		return deadline, nil
+	}
+
+	if date, err := time.ParseInLocation("2006-01-02", s, now.Location()); err == nil {
+		deadline := date.AddDate(0, 0, 1)
+		if !deadline.After(now) {
+			return time.Time{}, fmt.Errorf("date '%s' is in the past", s)
+		}
+		return deadline, nil
+	}
+
+	for _, layout := range deadlineLayouts {
+		if deadline, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			if !deadline.After(now) {
+				return time.Time{}, fmt.Errorf("time '%s' is in the past", s)
+			}
+			return deadline, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time '%s' (e.g. 18:00, 2026-01-31, 2026-01-31 18:00)", s)
+}
+
+// ExpiredDetach is a detach whose deadline has passed
+type ExpiredDetach struct {
+	*State
+	// Worktree is the worktree on the temporary branch, nil if there is none
+	Worktree *Worktree
+	// DirtyWorktrees are the worktrees a revert would switch that have
+	// uncommitted changes
+	DirtyWorktrees []string
+
+	Reverted bool
+	Err      error
+}
+
+// Overdue returns how long ago the detach expired
+func (e *ExpiredDetach) Overdue(now time.Time) time.Duration {
+	return now.Sub(e.Expires)
+}
+
+// formatDuration formats a duration in days, hours and minutes, like 3d4h or 25m
+func formatDuration(dur time.Duration) string {
+	dur = dur.Round(time.Minute)
+	days := int(dur / (24 * time.Hour))
+	hours := int(dur % (24 * time.Hour) / time.Hour)
+	minutes := int(dur % time.Hour / time.Minute)
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd%dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	}
+	return fmt.Sprintf("%dm", minutes)
+}
+
+// FindExpiredDetaches returns the detaches whose deadline is before now
+func (d *Detacher) FindExpiredDetaches(now time.Time) ([]*ExpiredDetach, error) {
+	states, err := d.ListTempBranches()
+	if err != nil {
+		return nil, err
+	}
+	worktrees, err := d.ListWorktrees()
+	if err != nil {
+		return nil, err
+	}
+
+	var expired []*ExpiredDetach
+	for _, st := range states {
+		if st.Expires.IsZero() || st.Expires.After(now) {
+			continue
+		}
+		e := &ExpiredDetach{
+			State:    st,
+			Worktree: FindWorktreeByBranch(worktrees, st.TempBranch, ""),
+		}
+		if e.Worktree != nil {
+			holders := []*Worktree{e.Worktree, FindWorktreeByBranch(worktrees, st.Origin, "")}
+			for _, wt := range holders {
+				if wt != nil && d.HasUncommittedChanges(wt.Path) {
+					e.DirtyWorktrees = append(e.DirtyWorktrees, wt.Path)
+				}
+			}
+		}
+		expired = append(expired, e)
+	}
+	return expired, nil
+}
+
+// ExpireDetaches reverts expired detaches. Detaches with uncommitted changes
+// are skipped unless opts.Force is set, and temporary branches that are not
+// checked out anywhere are left to gc.
+func (d *Detacher) ExpireDetaches(expired []*ExpiredDetach, opts *Options) error {
+	var errs []error
+	for _, e := range expired {
+		if e.Worktree == nil || (len(e.DirtyWorktrees) > 0 && !opts.Force) {
+			continue
+		}
+		revertOpts := &Options{Yes: true, Force: opts.Force, TempBranch: e.TempBranch}
+		if _, err := d.Revert(e.Origin, revertOpts); err != nil {
+			e.Err = err
+			errs = append(errs, fmt.Errorf("%s: %w", e.Origin, err))
+			continue
+		}
+		e.Reverted = true
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,147 @@
+package wtdetach
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseExpiry(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{input: "2h", expected: 2 * time.Hour},
+		{input: "90m", expected: 90 * time.Minute},
+		{input: "3d", expected: 72 * time.Hour},
+		{input: "0d", wantErr: true},
+		{input: "-1h", wantErr: true},
+		{input: "soon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			dur, err := ParseExpiry(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if dur != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, dur)
+			}
+		})
+	}
+}
+
+func TestParseDeadline(t *testing.T) {
+	now := time.Date(2026, 1, 15, 12, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		input    string
+		expected time.Time
+		wantErr  bool
+	}{
+		{input: "18:00", expected: time.Date(2026, 1, 15, 18, 0, 0, 0, time.Local)},
+		{input: "09:30", expected: time.Date(2026, 1, 16, 9, 30, 0, 0, time.Local)},
+		{input: "2026-01-31", expected: time.Date(2026, 2, 1, 0, 0, 0, 0, time.Local)},
+		{input: "2026-01-15", expected: time.Date(2026, 1, 16, 0, 0, 0, 0, time.Local)},
+		{input: "2026-01-31 18:00", expected: time.Date(2026, 1, 31, 18, 0, 0, 0, time.Local)},
+		{input: "2026-01-01", wantErr: true},
+		{input: "tomorrow", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			deadline, err := ParseDeadline(tt.input, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !deadline.Equal(tt.expected) {
+				t.Errorf("expected %s, got %s", tt.expected, deadline)
+			}
+		})
+	}
+}
+
+func TestIntegration_ExpireDetaches(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature-expired")
+	createBranch(t, repoDir, "feature-dirty")
+	createBranch(t, repoDir, "feature-later")
+	tempDir := resolvePath(t, t.TempDir())
+	expiredDir := filepath.Join(tempDir, "worktree-expired")
+	dirtyDir := filepath.Join(tempDir, "worktree-dirty")
+	laterDir := filepath.Join(tempDir, "worktree-later")
+	createWorktree(t, repoDir, expiredDir, "feature-expired")
+	createWorktree(t, repoDir, dirtyDir, "feature-dirty")
+	createWorktree(t, repoDir, laterDir, "feature-later")
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+	now := time.Now()
+	past := now.Add(-time.Hour).Truncate(time.Second)
+
+	for branch, expires := range map[string]time.Time{
+		"feature-expired": past,
+		"feature-dirty":   past,
+		"feature-later":   now.Add(time.Hour),
+	} {
+		if _, err := d.Detach(branch, &Options{Yes: true, Expires: expires}); err != nil {
+			t.Fatalf("Detach %s failed: %v", branch, err)
+		}
+	}
+	if st := d.LoadState("feature-expired__wt_detach"); !st.Expires.Equal(past) {
+		t.Errorf("expected deadline %s, got %s", past, st.Expires)
+	}
+	createUncommittedChange(t, dirtyDir)
+
+	expired, err := d.FindExpiredDetaches(now)
+	if err != nil {
+		t.Fatalf("FindExpiredDetaches failed: %v", err)
+	}
+	if len(expired) != 2 {
+		t.Fatalf("expected 2 expired detaches, got %d", len(expired))
+	}
+
+	if err := d.ExpireDetaches(expired, &Options{}); err != nil {
+		t.Fatalf("ExpireDetaches failed: %v", err)
+	}
+	for _, e := range expired {
+		switch e.Origin {
+		case "feature-expired":
+			if !e.Reverted {
+				t.Error("clean expired detach should be reverted")
+			}
+		case "feature-dirty":
+			if e.Reverted || len(e.DirtyWorktrees) != 1 {
+				t.Errorf("dirty expired detach should be kept: %+v", e)
+			}
+		}
+	}
+	if branch := getCurrentBranch(t, expiredDir); branch != "feature-expired" {
+		t.Errorf("worktree should be back on feature-expired, got %s", branch)
+	}
+	if branch := getCurrentBranch(t, dirtyDir); branch != "feature-dirty__wt_detach" {
+		t.Errorf("dirty worktree should stay detached, got %s", branch)
+	}
+	if branch := getCurrentBranch(t, laterDir); branch != "feature-later__wt_detach" {
+		t.Errorf("unexpired worktree should stay detached, got %s", branch)
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := map[time.Duration]string{
+		25 * time.Minute:                              "25m",
+		2*time.Hour + 5*time.Minute:                   "2h5m",
+		3*24*time.Hour + 4*time.Hour + 30*time.Second: "3d4h",
+	}
+	for dur, expected := range tests {
+		if got := formatDuration(dur); got != expected {
+			t.Errorf("formatDuration(%s): expected %s, got %s", dur, expected, got)
+		}
+	}
+}
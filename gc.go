@@ -0,0 +1,172 @@
+package wtdetach
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Classifications of a temporary branch against its original branch
+const (
+	// TempIdentical means the temp branch points at the same commit as the original
+	TempIdentical = "identical to original"
+	// TempMerged means all commits of the temp branch are in the original
+	TempMerged = "no extra commits"
+	// TempExtraCommits means the temp branch has commits not in the original
+	TempExtraCommits = "has extra commits"
+	// TempOriginDeleted means the original branch no longer exists
+	TempOriginDeleted = "original deleted"
+)
+
+// OrphanedTempBranch is a temporary branch that no existing worktree uses
+type OrphanedTempBranch struct {
+	*State
+	Reason       string
+	Status       string
+	ExtraCommits int
+	Deleted      bool
+
+	// missingWorktree is the missing worktree that still has the branch checked out
+	missingWorktree *Worktree
+}
+
+// Safe reports whether the branch can be deleted without losing commits
+func (o *OrphanedTempBranch) Safe() bool {
+	return o.Status == TempIdentical || o.Status == TempMerged
+}
+
+// MissingWorktree returns the path of the missing worktree that still has the
+// branch checked out, empty if there is none. It is removed along with the
+// branch.
+func (o *OrphanedTempBranch) MissingWorktree() string {
+	if o.missingWorktree == nil {
+		return ""
+	}
+	return o.missingWorktree.Path
+}
+
+// ClassifyTempBranch compares a temporary branch with its original branch
+func (d *Detacher) ClassifyTempBranch(tmpBranch, origin string) (string, int) {
+	if origin == "" || !d.BranchExists(origin) {
+		return TempOriginDeleted, 0
+	}
+
+	tmpHead, _ := d.git.Run("rev-parse", "refs/heads/"+tmpBranch)
+	originHead, _ := d.git.Run("rev-parse", "refs/heads/"+origin)
+	if tmpHead == originHead {
+		return TempIdentical, 0
+	}
+
+	count, err := d.git.Run("rev-list", "--count", "refs/heads/"+origin+"..refs/heads/"+tmpBranch)
+	if err != nil {
+		return TempExtraCommits, 0
+	}
+	n, _ := strconv.Atoi(count)
+	if n == 0 {
+		return TempMerged, 0
+	}
+	return TempExtraCommits, n
+}
+
+// FindOrphanedTempBranches returns the temporary branches that are not checked
+// out in any worktree, or only in worktrees whose directory is missing.
+// Missing worktrees locked by someone else (e.g. on an unmounted drive) are
+// considered in use.
+func (d *Detacher) FindOrphanedTempBranches() ([]*OrphanedTempBranch, error) {
+	states, err := d.ListTempBranches()
+	if err != nil {
+		return nil, err
+	}
+	worktrees, err := d.ListWorktrees()
+	if err != nil {
+		return nil, err
+	}
+
+	var orphans []*OrphanedTempBranch
+	for _, st := range states {
+		o := &OrphanedTempBranch{State: st}
+
+		wt := FindWorktreeByBranch(worktrees, st.TempBranch, "")
+		switch {
+		case wt == nil:
+			o.Reason = "not checked out"
+		case wt.Prunable || (IsOwnLock(wt) && !dirExists(wt.Path)):
+			o.Reason = fmt.Sprintf("worktree missing: %s", wt.Path)
+			o.missingWorktree = wt
+		default:
+			continue
+		}
+
+		o.Status, o.ExtraCommits = d.ClassifyTempBranch(st.TempBranch, st.Origin)
+		orphans = append(orphans, o)
+	}
+	return orphans, nil
+}
+
+// GC deletes orphaned temporary branches. Only safe ones are deleted unless
+// opts.Force is set, and nothing is deleted with opts.DryRun.
+func (d *Detacher) GC(opts *Options) ([]*OrphanedTempBranch, error) {
+	orphans, err := d.FindOrphanedTempBranches()
+	if err != nil {
+		return nil, err
+	}
+	if opts.DryRun {
+		return orphans, nil
+	}
+
+	var deletable []*OrphanedTempBranch
+	for _, o := range orphans {
+		if o.Safe() || opts.Force {
+			deletable = append(deletable, o)
+		}
+	}
+	return orphans, d.DeleteOrphans(deletable)
+}
+
+// GCSnapshots deletes the snapshots whose temporary branch no longer exists.
+// They may hold changes that revert could not restore, so they are only
+// deleted with opts.Force, and nothing is deleted with opts.DryRun.
+func (d *Detacher) GCSnapshots(opts *Options) ([]*StaleSnapshot, error) {
+	stale, err := d.FindStaleSnapshots()
+	if err != nil || opts.DryRun || !opts.Force {
+		return stale, err
+	}
+	for _, s := range stale {
+		d.DeleteSnapshot(s.Ref)
+		s.Deleted = true
+	}
+	return stale, nil
+}
+
+// DeleteOrphans deletes orphaned temporary branches regardless of their
+// classification, releasing their worktree locks first
+func (d *Detacher) DeleteOrphans(orphans []*OrphanedTempBranch) error {
+	for _, o := range orphans {
+		// A missing worktree must be removed before its branch can be deleted.
+		// Other missing worktrees are left alone.
+		if wt := o.missingWorktree; wt != nil {
+			if IsOwnLock(wt) {
+				if err := d.UnlockWorktree(wt.Path); err != nil {
+					return err
+				}
+			}
+			if err := d.RemoveMissingWorktree(wt.Path); err != nil {
+				return err
+			}
+		} else if _, err := d.unlockIfOwned(o.State); err != nil {
+			return err
+		}
+
+		if err := d.DeleteBranch(o.TempBranch); err != nil {
+			return err
+		}
+		d.DeleteSnapshot(SnapshotRef(o.TempBranch))
+		o.Deleted = true
+	}
+	return nil
+}
+
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,159 @@
+package wtdetach
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// findOrphan returns the orphaned temp branch with the given name
+func findOrphan(orphans []*OrphanedTempBranch, tmpBranch string) *OrphanedTempBranch {
+	for _, o := range orphans {
+		if o.TempBranch == tmpBranch {
+			return o
+		}
+	}
+	return nil
+}
+
+func TestIntegration_GC(t *testing.T) {
+	repoDir := setupTestRepo(t)
+
+	// An orphan identical to its original
+	createBranch(t, repoDir, "feature-same")
+	createBranch(t, repoDir, "feature-same__wt_detach")
+
+	// An orphan with a commit not in its original
+	createBranch(t, repoDir, "feature-extra")
+	runGit(t, repoDir, "checkout", "-b", "feature-extra__wt_detach")
+	runGit(t, repoDir, "commit", "--allow-empty", "-m", "extra")
+	runGit(t, repoDir, "checkout", "main")
+
+	// An orphan whose original was deleted
+	createBranch(t, repoDir, "gone__wt_detach")
+
+	// A temp branch in use
+	createBranch(t, repoDir, "feature-used")
+	usedDir := filepath.Join(resolvePath(t, t.TempDir()), "worktree-used")
+	createWorktree(t, repoDir, usedDir, "feature-used")
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+	if _, err := d.Detach("feature-used", &Options{Yes: true}); err != nil {
+		t.Fatalf("Detach failed: %v", err)
+	}
+
+	// Test: dry run classifies without deleting
+	orphans, err := d.GC(&Options{DryRun: true})
+	if err != nil {
+		t.Fatalf("GC dry run failed: %v", err)
+	}
+	if len(orphans) != 3 {
+		t.Fatalf("expected 3 orphans, got %d", len(orphans))
+	}
+	expected := map[string]string{
+		"feature-same__wt_detach":  TempIdentical,
+		"feature-extra__wt_detach": TempExtraCommits,
+		"gone__wt_detach":          TempOriginDeleted,
+	}
+	for name, status := range expected {
+		o := findOrphan(orphans, name)
+		if o == nil {
+			t.Errorf("%s should be orphaned", name)
+			continue
+		}
+		if o.Status != status {
+			t.Errorf("%s: expected status %q, got %q", name, status, o.Status)
+		}
+	}
+	if o := findOrphan(orphans, "feature-extra__wt_detach"); o != nil && o.ExtraCommits != 1 {
+		t.Errorf("expected 1 extra commit, got %d", o.ExtraCommits)
+	}
+	if !branchExistsInRepo(t, repoDir, "feature-same__wt_detach") {
+		t.Error("dry run should not delete branches")
+	}
+
+	// Test: only safe orphans are deleted by default
+	if _, err := d.GC(&Options{}); err != nil {
+		t.Fatalf("GC failed: %v", err)
+	}
+	if branchExistsInRepo(t, repoDir, "feature-same__wt_detach") {
+		t.Error("identical orphan should be deleted")
+	}
+	if !branchExistsInRepo(t, repoDir, "feature-extra__wt_detach") {
+		t.Error("orphan with extra commits should be kept")
+	}
+	if !branchExistsInRepo(t, repoDir, "gone__wt_detach") {
+		t.Error("orphan of a deleted branch should be kept")
+	}
+	if !branchExistsInRepo(t, repoDir, "feature-used__wt_detach") {
+		t.Error("temp branch in use should be kept")
+	}
+
+	// Test: --force deletes the remaining orphans
+	if _, err := d.GC(&Options{Force: true}); err != nil {
+		t.Fatalf("GC with force failed: %v", err)
+	}
+	if branchExistsInRepo(t, repoDir, "feature-extra__wt_detach") || branchExistsInRepo(t, repoDir, "gone__wt_detach") {
+		t.Error("orphans should be deleted with force")
+	}
+	if !branchExistsInRepo(t, repoDir, "feature-used__wt_detach") {
+		t.Error("temp branch in use should be kept with force")
+	}
+}
+
+func TestIntegration_GCMissingWorktree(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature-missing")
+	worktreeDir := filepath.Join(resolvePath(t, t.TempDir()), "worktree-missing")
+	createWorktree(t, repoDir, worktreeDir, "feature-missing")
+	createBranch(t, repoDir, "unrelated")
+	unrelatedDir := filepath.Join(resolvePath(t, t.TempDir()), "worktree-unrelated")
+	createWorktree(t, repoDir, unrelatedDir, "unrelated")
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+	if _, err := d.Detach("feature-missing", &Options{Yes: true}); err != nil {
+		t.Fatalf("Detach failed: %v", err)
+	}
+
+	// The detached worktree is removed behind our back, and so is one gc
+	// has nothing to do with
+	for _, dir := range []string{worktreeDir, unrelatedDir} {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Fatalf("failed to remove worktree: %v", err)
+		}
+	}
+
+	orphans, err := d.GC(&Options{})
+	if err != nil {
+		t.Fatalf("GC failed: %v", err)
+	}
+	o := findOrphan(orphans, "feature-missing__wt_detach")
+	if o == nil {
+		t.Fatal("temp branch in a missing worktree should be orphaned")
+	}
+	if !o.Deleted {
+		t.Errorf("temp branch should be deleted: %+v", o)
+	}
+	if branchExistsInRepo(t, repoDir, "feature-missing__wt_detach") {
+		t.Error("temp branch should be deleted")
+	}
+	registered := map[string]bool{}
+	worktrees, _ := d.ListWorktrees()
+	for _, wt := range worktrees {
+		registered[wt.Path] = true
+	}
+	if o.MissingWorktree() != worktreeDir || registered[worktreeDir] {
+		t.Errorf("missing worktree %s should be removed", worktreeDir)
+	}
+	if !registered[unrelatedDir] {
+		t.Error("unrelated missing worktree should be left alone")
+	}
+}
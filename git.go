@@ -1,6 +1,7 @@
 package wtdetach
 
 import (
+	"os"
 	"os/exec"
 	"strings"
 )
@@ -19,3 +20,11 @@ func (g *Git) RunInDir(dir string, args ...string) (string, error) {
 	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).Output()
 	return strings.TrimSpace(string(out)), err
 }
+
+// RunAttached executes a git command in a specific directory with the
+// standard streams of the process, for commands that talk to the user
+func (g *Git) RunAttached(dir string, args ...string) error {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
@@ -2,4 +2,4 @@ module github.com/Konboi/git-wt-detach
 
 go 1.24.2
 
-require github.com/alecthomas/kong v1.13.0 // indirect
+require github.com/alecthomas/kong v1.13.0
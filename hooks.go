@@ -0,0 +1,104 @@
+package wtdetach
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Names of the hooks run around detach and revert. A hook is an executable
+// with that name in the hooks directory, and the shell commands in
+// wt-detach.hook.<name without "wt-">, like wt-detach.hook.detach-pre.
+const (
+	HookDetachPre  = "wt-detach-pre"
+	HookDetachPost = "wt-detach-post"
+	HookRevertPre  = "wt-revert-pre"
+	HookRevertPost = "wt-revert-post"
+)
+
+// HookEnv describes the detach or revert to a hook, through WT_DETACH_*
+// environment variables. GIT_WT_DETACH_* would be taken for configuration
+// by a git wt-detach run from the hook.
+type HookEnv struct {
+	Branch     string
+	TempBranch string
+	// Worktree is the worktree switched to or from the temporary branch,
+	// empty if there is none
+	Worktree string
+	// CheckoutWorktree is the worktree the branch is checked out in after
+	// detaching, or until reverting, empty if there is none
+	CheckoutWorktree string
+}
+
+func (e *HookEnv) environ(name string) []string {
+	return append(os.Environ(),
+		"WT_DETACH_HOOK="+name,
+		"WT_DETACH_BRANCH="+e.Branch,
+		"WT_DETACH_TEMP_BRANCH="+e.TempBranch,
+		"WT_DETACH_WORKTREE="+e.Worktree,
+		"WT_DETACH_CHECKOUT_WORKTREE="+e.CheckoutWorktree,
+	)
+}
+
+// HookError reports a hook that failed
+type HookError struct {
+	Hook    string
+	Command string
+	Err     error
+}
+
+func (e *HookError) Error() string {
+	return fmt.Sprintf("%s hook failed: %s: %v", e.Hook, e.Command, e.Err)
+}
+
+func (e *HookError) Unwrap() error {
+	return e.Err
+}
+
+// RunHook runs the hook named name: the executable in the hooks directory,
+// then the configured commands, stopping at the first one that fails. Their
+// output goes to stderr, so that it does not mix with the output of
+// git wt-detach.
+func (d *Detacher) RunHook(name string, env *HookEnv) error {
+	type command struct {
+		desc string
+		cmd  *exec.Cmd
+	}
+	var commands []command
+	if path := d.hookPath(name); path != "" {
+		commands = append(commands, command{path, exec.Command(path)})
+	}
+	configured, _ := d.git.Run("config", "--get-all", "wt-detach.hook."+strings.TrimPrefix(name, "wt-"))
+	for _, line := range splitLines(configured) {
+		commands = append(commands, command{line, exec.Command("sh", "-c", line)})
+	}
+
+	for _, c := range commands {
+		c.cmd.Env = env.environ(name)
+		c.cmd.Stdout, c.cmd.Stderr = os.Stderr, os.Stderr
+		if err := c.cmd.Run(); err != nil {
+			return &HookError{Hook: name, Command: c.desc, Err: err}
+		}
+	}
+	return nil
+}
+
+// hookPath returns the path of the executable hook named name, empty if
+// there is none. The hooks directory honors core.hooksPath.
+func (d *Detacher) hookPath(name string) string {
+	dir, err := d.git.Run("rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return ""
+	}
+	path, err := filepath.Abs(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() || info.Mode()&0111 == 0 {
+		return ""
+	}
+	return path
+}
@@ -0,0 +1,112 @@
+package wtdetach
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeHook installs an executable hook script in the repository
+func writeHook(t *testing.T, repoDir, name, script string) {
+	t.Helper()
+	path := filepath.Join(repoDir, ".git", "hooks", name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatalf("failed to write hook: %v", err)
+	}
+}
+
+func TestIntegration_Hooks(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature-hooks")
+	worktreeDir := filepath.Join(resolvePath(t, t.TempDir()), "worktree-hooks")
+	createWorktree(t, repoDir, worktreeDir, "feature-hooks")
+
+	logFile := filepath.Join(t.TempDir(), "hooks.log")
+	record := `echo "$WT_DETACH_HOOK $WT_DETACH_BRANCH $WT_DETACH_TEMP_BRANCH $WT_DETACH_WORKTREE $WT_DETACH_CHECKOUT_WORKTREE" >> ` + logFile + "\n"
+	writeHook(t, repoDir, HookDetachPre, record)
+	writeHook(t, repoDir, HookRevertPost, record)
+	runGit(t, repoDir, "config", "wt-detach.hook.detach-post", record)
+	runGit(t, repoDir, "config", "wt-detach.hook.revert-pre", record)
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+	result, err := d.Detach("feature-hooks", &Options{Yes: true, Checkout: true})
+	if err != nil {
+		t.Fatalf("Detach failed: %v", err)
+	}
+	if result.HookErr != nil {
+		t.Errorf("unexpected hook error: %v", result.HookErr)
+	}
+	if result, err = d.Revert("feature-hooks", &Options{Yes: true}); err != nil {
+		t.Fatalf("Revert failed: %v", err)
+	}
+	if result.HookErr != nil {
+		t.Errorf("unexpected hook error: %v", result.HookErr)
+	}
+
+	log, _ := os.ReadFile(logFile)
+	want := []string{
+		"wt-detach-pre feature-hooks feature-hooks__wt_detach " + worktreeDir + " " + repoDir,
+		"wt-detach-post feature-hooks feature-hooks__wt_detach " + worktreeDir + " " + repoDir,
+		"wt-revert-pre feature-hooks feature-hooks__wt_detach " + worktreeDir + " " + repoDir,
+		"wt-revert-post feature-hooks feature-hooks__wt_detach " + worktreeDir + " " + repoDir,
+	}
+	if got := strings.TrimSpace(string(log)); got != strings.Join(want, "\n") {
+		t.Errorf("unexpected hook log:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+}
+
+func TestIntegration_FailingHooks(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature-veto")
+	worktreeDir := filepath.Join(resolvePath(t, t.TempDir()), "worktree-veto")
+	createWorktree(t, repoDir, worktreeDir, "feature-veto")
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+
+	// Test: a failing pre hook aborts the detach
+	runGit(t, repoDir, "config", "wt-detach.hook.detach-pre", "exit 3")
+	_, err := d.Detach("feature-veto", &Options{Yes: true})
+	var hookErr *HookError
+	if !errors.As(err, &hookErr) || hookErr.Hook != HookDetachPre || hookErr.Command != "exit 3" {
+		t.Fatalf("expected a wt-detach-pre hook error, got %v", err)
+	}
+	if branchExistsInRepo(t, repoDir, "feature-veto__wt_detach") {
+		t.Error("temp branch should not be created when the pre hook fails")
+	}
+	if branch := getCurrentBranch(t, worktreeDir); branch != "feature-veto" {
+		t.Errorf("worktree should stay on feature-veto, got %s", branch)
+	}
+
+	// Test: a failing post hook is reported, the detach stands
+	runGit(t, repoDir, "config", "--unset", "wt-detach.hook.detach-pre")
+	writeHook(t, repoDir, HookDetachPost, "exit 1\n")
+	result, err := d.Detach("feature-veto", &Options{Yes: true})
+	if err != nil {
+		t.Fatalf("Detach failed: %v", err)
+	}
+	if !errors.As(result.HookErr, &hookErr) || hookErr.Hook != HookDetachPost {
+		t.Errorf("expected a wt-detach-post hook error, got %v", result.HookErr)
+	}
+	if branch := getCurrentBranch(t, worktreeDir); branch != "feature-veto__wt_detach" {
+		t.Errorf("worktree should be on the temp branch, got %s", branch)
+	}
+
+	// Test: a failing pre hook aborts the revert
+	writeHook(t, repoDir, HookRevertPre, "exit 1\n")
+	if _, err := d.Revert("feature-veto", &Options{Yes: true}); !errors.As(err, &hookErr) {
+		t.Fatalf("expected a wt-revert-pre hook error, got %v", err)
+	}
+	if branch := getCurrentBranch(t, worktreeDir); branch != "feature-veto__wt_detach" {
+		t.Errorf("worktree should stay on the temp branch, got %s", branch)
+	}
+}
@@ -0,0 +1,139 @@
+package wtdetach
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Fallbacks for the worktree a branch is moved away from
+const (
+	// FallbackDetached leaves the worktree on a detached HEAD at the same commit
+	FallbackDetached = "detached"
+	// FallbackDefault switches the worktree to the repository's default branch
+	FallbackDefault = "default"
+)
+
+// MoveResult represents the result of a move
+type MoveResult struct {
+	WorktreePath string
+	CurrentPath  string
+	// Fallback is the branch the worktree was switched to, empty for detached HEAD
+	Fallback string
+}
+
+// DefaultBranch returns the default branch of the repository: the branch
+// origin/HEAD points at, init.defaultBranch, or else main or master
+func (d *Detacher) DefaultBranch() (string, error) {
+	if ref, err := d.git.Run("symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD"); err == nil {
+		if branch := strings.TrimPrefix(ref, "origin/"); d.BranchExists(branch) {
+			return branch, nil
+		}
+	}
+	if branch, err := d.git.Run("config", "--get", "init.defaultBranch"); err == nil && d.BranchExists(branch) {
+		return branch, nil
+	}
+	for _, branch := range []string{"main", "master"} {
+		if d.BranchExists(branch) {
+			return branch, nil
+		}
+	}
+	return "", fmt.Errorf("could not determine the default branch")
+}
+
+// ResolveFallback returns the branch a worktree falls back to after a move,
+// empty for a detached HEAD
+func (d *Detacher) ResolveFallback(fallback string) (string, error) {
+	switch fallback {
+	case "", FallbackDetached:
+		return "", nil
+	case FallbackDefault:
+		return d.DefaultBranch()
+	default:
+		if !d.BranchExists(fallback) {
+			return "", fmt.Errorf("fallback branch '%s' does not exist", fallback)
+		}
+		return fallback, nil
+	}
+}
+
+// Move permanently moves a branch checked out in another worktree to the
+// current worktree. The other worktree is left on the fallback instead of a
+// temporary branch, so there is nothing to revert.
+func (d *Detacher) Move(branch, fallback string, opts *Options) (*MoveResult, error) {
+	if !d.BranchExists(branch) {
+		return nil, fmt.Errorf("branch '%s' does not exist", branch)
+	}
+
+	currentPath, err := d.GetCurrentWorktreePath()
+	if err != nil {
+		return nil, err
+	}
+
+	wt, err := d.FindWorktreeForBranch(branch)
+	if err != nil {
+		return nil, err
+	}
+	if wt == nil {
+		if opts.DryRun {
+			return &MoveResult{CurrentPath: currentPath}, nil
+		}
+		if err := d.Checkout(currentPath, branch); err != nil {
+			return nil, err
+		}
+		return &MoveResult{CurrentPath: currentPath}, nil
+	}
+
+	if d.HasUncommittedChanges(wt.Path) && !opts.Force {
+		return nil, fmt.Errorf("uncommitted changes found in worktree: %s\n  Use --force to override", wt.Path)
+	}
+
+	fallbackBranch, err := d.ResolveFallback(fallback)
+	if err != nil {
+		return nil, err
+	}
+	if fallbackBranch == branch {
+		return nil, fmt.Errorf("fallback branch cannot be the moved branch '%s'", branch)
+	}
+	if fallbackBranch != "" {
+		worktrees, err := d.ListWorktrees()
+		if err != nil {
+			return nil, err
+		}
+		// The current worktree releases its branch during the move
+		if holder := FindWorktreeByBranch(worktrees, fallbackBranch, currentPath); holder != nil {
+			return nil, fmt.Errorf("fallback branch '%s' is checked out in worktree: %s", fallbackBranch, holder.Path)
+		}
+	}
+
+	result := &MoveResult{
+		WorktreePath: wt.Path,
+		CurrentPath:  currentPath,
+		Fallback:     fallbackBranch,
+	}
+	if opts.DryRun {
+		return result, nil
+	}
+
+	prevBranch, prevHead, err := d.CurrentHead(currentPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.CheckoutDetached(wt.Path, "HEAD"); err != nil {
+		return nil, err
+	}
+
+	if err := d.Checkout(currentPath, branch); err != nil {
+		return nil, fmt.Errorf("%w\n  %s", err, d.switchBack(wt.Path, branch, ""))
+	}
+
+	if fallbackBranch != "" {
+		if err := d.Checkout(wt.Path, fallbackBranch); err != nil {
+			return nil, fmt.Errorf("%w\n  %s\n  %s", err,
+				d.switchBack(currentPath, prevBranch, prevHead),
+				d.switchBack(wt.Path, branch, ""))
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,129 @@
+package wtdetach
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIntegration_Move(t *testing.T) {
+	tests := []struct {
+		name     string
+		fallback string
+		expected string
+	}{
+		{name: "detached HEAD", fallback: FallbackDetached, expected: "HEAD"},
+		{name: "default branch", fallback: FallbackDefault, expected: "main"},
+		{name: "named branch", fallback: "parking", expected: "parking"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repoDir := setupTestRepo(t)
+			createBranch(t, repoDir, "feature-move")
+			createBranch(t, repoDir, "parking")
+			createBranch(t, repoDir, "here")
+			runGit(t, repoDir, "checkout", "here")
+			worktreeDir := filepath.Join(resolvePath(t, t.TempDir()), "worktree-move")
+			createWorktree(t, repoDir, worktreeDir, "feature-move")
+
+			oldWd, _ := os.Getwd()
+			os.Chdir(repoDir)
+			defer os.Chdir(oldWd)
+
+			d := NewDetacher()
+			result, err := d.Move("feature-move", tt.fallback, &Options{Yes: true})
+			if err != nil {
+				t.Fatalf("Move failed: %v", err)
+			}
+			if result.WorktreePath != worktreeDir {
+				t.Errorf("WorktreePath: expected %s, got %s", worktreeDir, result.WorktreePath)
+			}
+			if branch := getCurrentBranch(t, repoDir); branch != "feature-move" {
+				t.Errorf("current worktree should be on feature-move, got %s", branch)
+			}
+			if branch := getCurrentBranch(t, worktreeDir); branch != tt.expected {
+				t.Errorf("other worktree should be on %s, got %s", tt.expected, branch)
+			}
+			if branchExistsInRepo(t, repoDir, "feature-move__wt_detach") {
+				t.Error("move should not create a temp branch")
+			}
+		})
+	}
+}
+
+func TestIntegration_MoveFallbackCheckedOutElsewhere(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature-move")
+	createBranch(t, repoDir, "busy")
+	worktreeDir := filepath.Join(resolvePath(t, t.TempDir()), "worktree-move")
+	createWorktree(t, repoDir, worktreeDir, "feature-move")
+	busyDir := filepath.Join(resolvePath(t, t.TempDir()), "worktree-busy")
+	createWorktree(t, repoDir, busyDir, "busy")
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+
+	if _, err := d.Move("feature-move", "busy", &Options{Yes: true}); err == nil {
+		t.Fatal("Move should fail when the fallback branch is checked out elsewhere")
+	}
+	if branch := getCurrentBranch(t, worktreeDir); branch != "feature-move" {
+		t.Errorf("other worktree should still be on feature-move, got %s", branch)
+	}
+
+	// Test: the current worktree's branch can be the fallback
+	if _, err := d.Move("feature-move", "main", &Options{Yes: true}); err != nil {
+		t.Fatalf("Move failed: %v", err)
+	}
+	if branch := getCurrentBranch(t, worktreeDir); branch != "main" {
+		t.Errorf("other worktree should be on main, got %s", branch)
+	}
+}
+
+func TestIntegration_MoveRollback(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature-back")
+
+	// main gets a file that collides with an untracked file in the other worktree
+	if err := os.WriteFile(filepath.Join(repoDir, "conflict.txt"), []byte("main\n"), 0644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	runGit(t, repoDir, "add", "conflict.txt")
+	runGit(t, repoDir, "commit", "-m", "add conflict.txt")
+
+	worktreeDir := filepath.Join(resolvePath(t, t.TempDir()), "worktree-back")
+	createWorktree(t, repoDir, worktreeDir, "feature-back")
+	if err := os.WriteFile(filepath.Join(worktreeDir, "conflict.txt"), []byte("untracked\n"), 0644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+
+	_, err := d.Move("feature-back", "main", &Options{Yes: true, Force: true})
+	if err == nil {
+		t.Fatal("Move should fail when the other worktree cannot checkout the fallback")
+	}
+	for _, want := range []string{
+		"Worktree " + repoDir + " was switched back to 'main'",
+		"Worktree " + worktreeDir + " was switched back to 'feature-back'",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error should report %q, got: %v", want, err)
+		}
+	}
+
+	if branch := getCurrentBranch(t, repoDir); branch != "main" {
+		t.Errorf("current worktree should be back on main, got %s", branch)
+	}
+	if branch := getCurrentBranch(t, worktreeDir); branch != "feature-back" {
+		t.Errorf("other worktree should be back on feature-back, got %s", branch)
+	}
+}
@@ -0,0 +1,316 @@
+package wtdetach
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Placeholders available in wt-detach.nameTemplate
+const (
+	placeholderBranch   = "{branch}"
+	placeholderWorktree = "{worktree}"
+	placeholderUser     = "{user}"
+	placeholderDate     = "{date}"
+)
+
+// dateLayout is the layout used for the {date} placeholder
+const dateLayout = "20060102"
+
+// NameVars holds the values substituted into a name template
+type NameVars struct {
+	Branch   string
+	Worktree string
+	User     string
+	Date     time.Time
+}
+
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// sanitizeNameComponent replaces characters that are not safe in a ref component
+func sanitizeNameComponent(s string) string {
+	s = unsafeNameChars.ReplaceAllString(s, "-")
+	return strings.Trim(s, "-.")
+}
+
+// ValidateNameTemplate checks that a name template can be resolved in reverse
+func ValidateNameTemplate(tmpl string) error {
+	if strings.Count(tmpl, placeholderBranch) != 1 {
+		return fmt.Errorf("invalid name template '%s': must contain %s exactly once", tmpl, placeholderBranch)
+	}
+	if tmpl == placeholderBranch {
+		return fmt.Errorf("invalid name template '%s': must differ from the branch name", tmpl)
+	}
+	return nil
+}
+
+// ExpandNameTemplate substitutes the placeholders of a name template
+func ExpandNameTemplate(tmpl string, vars NameVars) string {
+	return strings.NewReplacer(
+		placeholderBranch, vars.Branch,
+		placeholderWorktree, sanitizeNameComponent(vars.Worktree),
+		placeholderUser, sanitizeNameComponent(vars.User),
+		placeholderDate, vars.Date.Format(dateLayout),
+	).Replace(tmpl)
+}
+
+// NameTemplatePattern returns a regexp matching the names a template expands to
+// for the given branch
+func NameTemplatePattern(tmpl, branch string) *regexp.Regexp {
+	return regexp.MustCompile(nameTemplateExpr(tmpl, regexp.QuoteMeta(branch)))
+}
+
+// ParseNameTemplate extracts the original branch from a name the template expanded to
+func ParseNameTemplate(tmpl, name string) (string, bool) {
+	m := regexp.MustCompile(nameTemplateExpr(tmpl, "(.+)")).FindStringSubmatch(name)
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
+
+// nameTemplateExpr converts a template into a regular expression,
+// using branchExpr for the {branch} placeholder
+func nameTemplateExpr(tmpl, branchExpr string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for rest := tmpl; rest != ""; {
+		i := strings.Index(rest, "{")
+		j := strings.Index(rest, "}")
+		if i < 0 || j < i {
+			b.WriteString(regexp.QuoteMeta(rest))
+			break
+		}
+		b.WriteString(regexp.QuoteMeta(rest[:i]))
+		switch token := rest[i : j+1]; token {
+		case placeholderBranch:
+			b.WriteString(branchExpr)
+		case placeholderWorktree, placeholderUser:
+			b.WriteString(`[A-Za-z0-9._-]*`)
+		case placeholderDate:
+			b.WriteString(`[0-9]{8}`)
+		default:
+			b.WriteString(regexp.QuoteMeta(token))
+		}
+		rest = rest[j+1:]
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// currentUserName returns the login name of the current user
+func currentUserName() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return filepath.Base(u.Username)
+	}
+	return os.Getenv("USER")
+}
+
+// SetNameTemplate sets the template for temporary branch names
+func (d *Detacher) SetNameTemplate(tmpl string) error {
+	if tmpl == "" {
+		d.template = ""
+		return nil
+	}
+	if err := ValidateNameTemplate(tmpl); err != nil {
+		return err
+	}
+	d.template = tmpl
+	return nil
+}
+
+// LoadNameTemplateFromConfig loads the name template from git config
+func (d *Detacher) LoadNameTemplateFromConfig() error {
+	tmpl := d.ConfigString("nameTemplate")
+	if tmpl == "" {
+		return nil
+	}
+	return d.SetNameTemplate(tmpl)
+}
+
+// TempBranchNameFor returns the temporary branch name for a branch checked
+// out in wt. The name is validated with `git check-ref-format`.
+func (d *Detacher) TempBranchNameFor(branch string, wt *Worktree) (string, error) {
+	name := d.TempBranchName(branch)
+	if d.template != "" {
+		vars := NameVars{
+			Branch: branch,
+			User:   currentUserName(),
+			Date:   time.Now(),
+		}
+		if wt != nil {
+			vars.Worktree = filepath.Base(wt.Path)
+		}
+		name = ExpandNameTemplate(d.template, vars)
+	}
+
+	if _, err := d.git.Run("check-ref-format", "--branch", name); err != nil {
+		return "", fmt.Errorf("invalid temporary branch name '%s'", name)
+	}
+	return name, nil
+}
+
+// FindTempBranch returns the existing temporary branch for a branch.
+// Temporary branches recording branch as their origin are preferred, so that
+// they are found even if the suffix or template changed after the detach.
+// Otherwise, with a name template, local branches are matched against the
+// template in reverse.
+func (d *Detacher) FindTempBranch(branch string) (string, error) {
+	states, err := d.ListStates()
+	if err != nil {
+		return "", err
+	}
+	var recorded []string
+	for _, st := range states {
+		if st.Origin == branch {
+			recorded = append(recorded, st.TempBranch)
+		}
+	}
+	switch len(recorded) {
+	case 0:
+	case 1:
+		return recorded[0], nil
+	default:
+		return "", fmt.Errorf("multiple temporary branches found for '%s': %s\n  Use --temp-branch to choose one", branch, strings.Join(recorded, ", "))
+	}
+
+	if d.template == "" {
+		tmpBranch := d.TempBranchName(branch)
+		if !d.BranchExists(tmpBranch) {
+			return "", fmt.Errorf("temporary branch '%s' does not exist", tmpBranch)
+		}
+		return tmpBranch, nil
+	}
+
+	branches, err := d.ListBranches()
+	if err != nil {
+		return "", err
+	}
+
+	pattern := NameTemplatePattern(d.template, branch)
+	var matches []string
+	for _, name := range branches {
+		if name != branch && pattern.MatchString(name) {
+			matches = append(matches, name)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("temporary branch for '%s' does not exist (template: %s)", branch, d.template)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("multiple temporary branches found for '%s': %s\n  Use --temp-branch to choose one", branch, strings.Join(matches, ", "))
+	}
+}
+
+// resolveTempBranch returns the temporary branch given by the user, or finds it.
+// A given branch must be a temporary branch of branch, since revert deletes it.
+func (d *Detacher) resolveTempBranch(branch, tmpBranch string) (string, error) {
+	if tmpBranch == "" {
+		return d.FindTempBranch(branch)
+	}
+	if !d.BranchExists(tmpBranch) {
+		return "", fmt.Errorf("temporary branch '%s' does not exist", tmpBranch)
+	}
+	origin, err := d.OriginOf(tmpBranch)
+	if err != nil {
+		return "", err
+	}
+	if origin != branch {
+		return "", fmt.Errorf("temporary branch '%s' was created for '%s', not '%s'", tmpBranch, origin, branch)
+	}
+	return tmpBranch, nil
+}
+
+// OriginOf returns the original branch of a temporary branch, from the
+// recorded metadata or else by resolving the suffix or template in reverse
+func (d *Detacher) OriginOf(tmpBranch string) (string, error) {
+	if origin := d.getBranchConfig(tmpBranch, stateKeyOrigin); origin != "" {
+		return origin, nil
+	}
+	if origin, ok := d.originFromName(tmpBranch); ok {
+		return origin, nil
+	}
+	return "", fmt.Errorf("'%s' is not a temporary branch", tmpBranch)
+}
+
+// originFromName resolves the suffix or template in reverse
+func (d *Detacher) originFromName(tmpBranch string) (string, bool) {
+	if d.template != "" {
+		return ParseNameTemplate(d.template, tmpBranch)
+	}
+	origin := strings.TrimSuffix(tmpBranch, d.suffix)
+	return origin, origin != tmpBranch && origin != ""
+}
+
+// ListTempBranches returns all temporary branches, recognized by their
+// recorded metadata or else by the current suffix or template
+func (d *Detacher) ListTempBranches() ([]*State, error) {
+	states, err := d.ListStates()
+	if err != nil {
+		return nil, err
+	}
+	recorded := make(map[string]bool, len(states))
+	for _, st := range states {
+		recorded[st.TempBranch] = true
+	}
+
+	branches, err := d.ListBranches()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range branches {
+		if recorded[name] {
+			continue
+		}
+		if origin, ok := d.originFromName(name); ok {
+			states = append(states, &State{TempBranch: name, Origin: origin})
+		}
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].TempBranch < states[j].TempBranch
+	})
+	return states, nil
+}
+
+// CurrentTempBranch returns the temporary branch checked out in the current
+// worktree and its original branch
+func (d *Detacher) CurrentTempBranch() (tmpBranch, origin string, err error) {
+	currentPath, err := d.GetCurrentWorktreePath()
+	if err != nil {
+		return "", "", err
+	}
+	tmpBranch, _, err = d.CurrentHead(currentPath)
+	if err != nil {
+		return "", "", err
+	}
+	if tmpBranch == "" {
+		return "", "", fmt.Errorf("current worktree is not on a temporary branch (HEAD is detached)")
+	}
+	origin, err = d.OriginOf(tmpBranch)
+	if err != nil {
+		return "", "", fmt.Errorf("current worktree is not on a temporary branch: %w", err)
+	}
+	return tmpBranch, origin, nil
+}
+
+// TempBranchOf returns the temporary branch checked out in a worktree and its
+// original branch
+func (d *Detacher) TempBranchOf(wt *Worktree) (tmpBranch, origin string, err error) {
+	if wt.Branch == "" {
+		return "", "", fmt.Errorf("worktree %s is not on a temporary branch (HEAD is detached)", wt.Path)
+	}
+	origin, err = d.OriginOf(wt.Branch)
+	if err != nil {
+		return "", "", fmt.Errorf("worktree %s is not on a temporary branch: %w", wt.Path, err)
+	}
+	return wt.Branch, origin, nil
+}
@@ -0,0 +1,152 @@
+package wtdetach
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpandNameTemplate(t *testing.T) {
+	vars := NameVars{
+		Branch:   "feature/x",
+		Worktree: "repo wt",
+		User:     "alice",
+		Date:     time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name     string
+		tmpl     string
+		expected string
+	}{
+		{
+			name:     "suffix style",
+			tmpl:     "{branch}__tmp",
+			expected: "feature/x__tmp",
+		},
+		{
+			name:     "namespace with worktree",
+			tmpl:     "wt-detach/{worktree}/{branch}",
+			expected: "wt-detach/repo-wt/feature/x",
+		},
+		{
+			name:     "user and date",
+			tmpl:     "wt-detach/{user}/{date}/{branch}",
+			expected: "wt-detach/alice/20260102/feature/x",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExpandNameTemplate(tt.tmpl, vars); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNameTemplatePattern(t *testing.T) {
+	tests := []struct {
+		name     string
+		tmpl     string
+		branch   string
+		input    string
+		expected bool
+	}{
+		{
+			name:     "worktree namespace",
+			tmpl:     "wt-detach/{worktree}/{branch}",
+			branch:   "feature/x",
+			input:    "wt-detach/repo-wt/feature/x",
+			expected: true,
+		},
+		{
+			name:     "different branch",
+			tmpl:     "wt-detach/{worktree}/{branch}",
+			branch:   "feature/x",
+			input:    "wt-detach/repo-wt/feature/y",
+			expected: false,
+		},
+		{
+			name:     "date",
+			tmpl:     "{branch}-{date}",
+			branch:   "main",
+			input:    "main-20260102",
+			expected: true,
+		},
+		{
+			name:     "date is not a date",
+			tmpl:     "{branch}-{date}",
+			branch:   "main",
+			input:    "main-latest",
+			expected: false,
+		},
+		{
+			name:     "regexp characters in template",
+			tmpl:     "{branch}.tmp",
+			branch:   "main",
+			input:    "main_tmp",
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pattern := NameTemplatePattern(tt.tmpl, tt.branch)
+			if got := pattern.MatchString(tt.input); got != tt.expected {
+				t.Errorf("%s matching %q: expected %v, got %v", pattern, tt.input, tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestValidateNameTemplate(t *testing.T) {
+	if err := ValidateNameTemplate("wt-detach/{worktree}/{branch}"); err != nil {
+		t.Errorf("template should be valid: %v", err)
+	}
+	if err := ValidateNameTemplate("wt-detach/{worktree}"); err == nil {
+		t.Error("template without {branch} should be invalid")
+	}
+	if err := ValidateNameTemplate("{branch}/{branch}"); err == nil {
+		t.Error("template with {branch} twice should be invalid")
+	}
+}
+
+func TestParseNameTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		tmpl     string
+		input    string
+		expected string
+		ok       bool
+	}{
+		{
+			name:     "worktree namespace",
+			tmpl:     "wt-detach/{worktree}/{branch}",
+			input:    "wt-detach/repo-wt/feature/x",
+			expected: "feature/x",
+			ok:       true,
+		},
+		{
+			name:     "suffix style",
+			tmpl:     "{branch}__tmp",
+			input:    "feature-x__tmp",
+			expected: "feature-x",
+			ok:       true,
+		},
+		{
+			name:  "not matching",
+			tmpl:  "wt-detach/{worktree}/{branch}",
+			input: "feature-x",
+			ok:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseNameTemplate(tt.tmpl, tt.input)
+			if ok != tt.ok || got != tt.expected {
+				t.Errorf("expected (%q, %v), got (%q, %v)", tt.expected, tt.ok, got, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package wtdetach
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// stdin is shared by all prompts, so that none of them loses input buffered
+// by another
+var stdin = bufio.NewReader(os.Stdin)
+
+// ExitAborted is the exit code when the user aborts a command at a prompt
+const ExitAborted = 2
+
+// errAborted ends a command the user aborted, once "Aborted." is printed
+var errAborted = &ExitError{Code: ExitAborted}
+
+// Values of wt-detach.confirm, which tells when to ask before switching
+// worktrees
+const (
+	ConfirmAlways    = "always"
+	ConfirmNever     = "never"
+	ConfirmDirtyOnly = "dirty-only"
+)
+
+// shouldConfirm reports whether to ask before switching worktrees: unless
+// --yes is given, as wt-detach.confirm says, where dirty tells whether any of
+// them has uncommitted changes. Asking requires stdin to be a terminal, so
+// that a script does not hang or abort on a prompt it cannot answer.
+func shouldConfirm(d *Detacher, yes, dirty bool) (bool, error) {
+	if yes {
+		return false, nil
+	}
+	switch mode := d.ConfigString("confirm"); mode {
+	case "", ConfirmAlways:
+	case ConfirmNever:
+		return false, nil
+	case ConfirmDirtyOnly:
+		if !dirty {
+			return false, nil
+		}
+	default:
+		return false, fmt.Errorf("invalid wt-detach.confirm '%s' (always, never, or dirty-only)", mode)
+	}
+	if !isTerminal(os.Stdin) {
+		return false, fmt.Errorf("confirmation required, but stdin is not a terminal\n  Use --yes to proceed without confirmation")
+	}
+	return true, nil
+}
+
+// proceed asks whether to go ahead, and returns errAborted if not
+func proceed() error {
+	fmt.Print("Proceed? [y/N] ")
+	if !readYesNo() {
+		fmt.Println("Aborted.")
+		return errAborted
+	}
+	return nil
+}
+
+// interactive reports whether the user can be asked questions
+func interactive() bool {
+	return isTerminal(os.Stdin) && isTerminal(os.Stdout)
+}
+
+// dirtyResolver deals with worktrees that have uncommitted changes before a
+// command switches them. Without a terminal, or with --yes or --dry-run, it
+// fails like before; on a terminal it lets the user decide what to do.
+type dirtyResolver struct {
+	d           *Detacher
+	force       *bool
+	interactive bool
+	// action describes the command, for stash and commit messages
+	action  string
+	stashes []*Stash
+	// found is set once uncommitted changes are found in a worktree
+	found bool
+}
+
+func newDirtyResolver(d *Detacher, force *bool, dryRun, yes bool, action string) *dirtyResolver {
+	return &dirtyResolver{
+		d:           d,
+		force:       force,
+		interactive: !dryRun && !yes && interactive(),
+		action:      action,
+	}
+}
+
+// check returns an error unless the command can go ahead with the worktree
+// at path
+func (r *dirtyResolver) check(path string) error {
+	if !r.d.HasUncommittedChanges(path) {
+		return nil
+	}
+	r.found = true
+	if *r.force {
+		fmt.Printf("⚠ Warning: Uncommitted changes found in worktree: %s\n", path)
+		return nil
+	}
+	if !r.interactive {
+		return formatUncommittedError(path, r.d.GetUncommittedFiles(path))
+	}
+
+	files := r.d.UncommittedStatus(path)
+	branch := r.commitBranch(path)
+	for {
+		fmt.Printf("Uncommitted changes found in worktree: %s\n", path)
+		for i, f := range files {
+			if i == maxReportLines {
+				fmt.Printf("  ... and %d more\n", len(files)-maxReportLines)
+				break
+			}
+			fmt.Printf("  %s %s\n", f.Status, f.Path)
+		}
+		fmt.Println()
+		fmt.Println("  [s] Stash them, and restore them afterwards")
+		if branch != "" {
+			fmt.Printf("  [c] Commit them as a WIP commit on '%s'\n", branch)
+		}
+		fmt.Println("  [f] Carry them along, like --force")
+		fmt.Println("  [d] Show the diff")
+		fmt.Println("  [a] Abort")
+		fmt.Print("What now? ")
+
+		choice, ok := readLine()
+		switch {
+		case !ok || choice == "a":
+			fmt.Println("Aborted.")
+			return errAborted
+		case choice == "s":
+			s, err := r.d.StashChanges(path, "wt-detach: set aside to "+r.action)
+			if err != nil {
+				return err
+			}
+			if s != nil {
+				r.stashes = append(r.stashes, s)
+				fmt.Printf("✔ Stashed uncommitted changes: %s\n", shortHash(s.Commit))
+			}
+			return nil
+		case choice == "c" && branch != "":
+			commit, err := r.d.CommitWIP(path, "WIP: uncommitted changes set aside to "+r.action)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("✔ Committed uncommitted changes: %s on %s\n", shortHash(commit), branch)
+			return nil
+		case choice == "f":
+			*r.force = true
+			fmt.Printf("⚠ Warning: Uncommitted changes found in worktree: %s\n", path)
+			return nil
+		case choice == "d":
+			if err := r.d.ShowDiff(path); err != nil {
+				fmt.Printf("⚠ Could not show the diff: %s\n", err)
+			}
+		}
+		fmt.Println()
+	}
+}
+
+// commitBranch returns the branch a WIP commit in the worktree would land
+// on, or empty if there is none worth keeping it on: on a detached HEAD or a
+// temporary branch, it would be left behind by the command.
+func (r *dirtyResolver) commitBranch(path string) string {
+	branch, _, err := r.d.CurrentHead(path)
+	if err != nil || branch == "" {
+		return ""
+	}
+	if _, err := r.d.OriginOf(branch); err == nil {
+		return ""
+	}
+	return branch
+}
+
+// restore restores the stashed changes, in the worktrees they were taken in
+func (r *dirtyResolver) restore() error {
+	var errs []error
+	for i := len(r.stashes) - 1; i >= 0; i-- {
+		s := r.stashes[i]
+		if err := r.d.RestoreStash(s); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		fmt.Printf("✔ Restored stashed changes in: %s\n", s.Path)
+	}
+	r.stashes = nil
+	return errors.Join(errs...)
+}
+
+// finish restores the stashed changes, adding a failure to do so to *err
+func (r *dirtyResolver) finish(err *error) {
+	if rerr := r.restore(); rerr != nil {
+		*err = errors.Join(*err, rerr)
+	}
+}
+
+// pickCandidate lets the user pick a branch from a list, which typing part of
+// a branch or worktree name narrows down. It returns nil if the user aborts.
+func pickCandidate(title string, candidates []Candidate) *Candidate {
+	shown := candidates
+	for {
+		fmt.Println(title)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		dirty := false
+		now := time.Now()
+		for i, c := range shown {
+			where := c.Worktree
+			switch {
+			case where == "":
+				where = "(not checked out)"
+			case c.Dirty:
+				where += " *"
+				dirty = true
+			}
+			subject := c.Subject
+			if overdue := c.Overdue(now); overdue > 0 {
+				subject += fmt.Sprintf("  (expired %s ago)", formatDuration(overdue))
+			}
+			fmt.Fprintf(w, "  %d)\t%s\t%s\t%s\n", i+1, c.Branch, where, subject)
+		}
+		w.Flush()
+		if dirty {
+			fmt.Println("  (* has uncommitted changes)")
+		}
+		fmt.Print("\nNumber, or text to filter (empty to abort): ")
+
+		input, ok := readLine()
+		if !ok || input == "" {
+			return nil
+		}
+		if n, err := strconv.Atoi(input); err == nil && n >= 1 && n <= len(shown) {
+			return &shown[n-1]
+		}
+		if matches := FilterCandidates(candidates, input); len(matches) > 0 {
+			shown = matches
+		} else {
+			fmt.Printf("No branch matches '%s'\n", input)
+		}
+		fmt.Println()
+	}
+}
+
+// readLine reads a line from stdin, trimmed and lowercased. It returns false
+// at the end of input.
+func readLine() (string, bool) {
+	input, err := stdin.ReadString('\n')
+	if err != nil && input == "" {
+		return "", false
+	}
+	return strings.TrimSpace(strings.ToLower(input)), true
+}
@@ -0,0 +1,60 @@
+package wtdetach
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestIntegration_ShouldConfirm(t *testing.T) {
+	repoDir := setupTestRepo(t)
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	// Stdin is a pipe, as in a script
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	oldStdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = oldStdin }()
+
+	d := NewDetacher()
+	tests := []struct {
+		confirm string
+		yes     bool
+		dirty   bool
+		wantErr string
+	}{
+		{confirm: "", yes: true},
+		{confirm: "", wantErr: "stdin is not a terminal"},
+		{confirm: "always", dirty: true, wantErr: "stdin is not a terminal"},
+		{confirm: "never", dirty: true},
+		{confirm: "dirty-only"},
+		{confirm: "dirty-only", dirty: true, wantErr: "stdin is not a terminal"},
+		{confirm: "sometimes", wantErr: "invalid wt-detach.confirm"},
+	}
+
+	for _, tt := range tests {
+		// Unset cases come first
+		if tt.confirm != "" {
+			runGit(t, repoDir, "config", "wt-detach.confirm", tt.confirm)
+		}
+
+		ask, err := shouldConfirm(d, tt.yes, tt.dirty)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("confirm=%q yes=%v dirty=%v: expected error containing %q, got %v", tt.confirm, tt.yes, tt.dirty, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil || ask {
+			t.Errorf("confirm=%q yes=%v dirty=%v: expected no prompt, got %v, %v", tt.confirm, tt.yes, tt.dirty, ask, err)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package wtdetach
+
+import (
+	"fmt"
+)
+
+// RefreshResult represents the result of refreshing a temporary branch
+type RefreshResult struct {
+	*State
+	// WorktreePath is the worktree on the temporary branch, empty if none
+	WorktreePath string
+	From         string
+	To           string
+	UpToDate     bool
+}
+
+// Refresh fast-forwards the temporary branch of a detached branch, and the
+// worktree it is checked out in, to the original branch. It refuses when the
+// temporary branch has commits the original branch does not have.
+func (d *Detacher) Refresh(branch string, opts *Options) (*RefreshResult, error) {
+	tmpBranch, err := d.resolveTempBranch(branch, opts.TempBranch)
+	if err != nil {
+		return nil, err
+	}
+	result := &RefreshResult{State: d.LoadState(tmpBranch)}
+	if result.Origin == "" {
+		result.Origin = branch
+	}
+
+	switch status, n := d.ClassifyTempBranch(tmpBranch, branch); status {
+	case TempOriginDeleted:
+		return nil, fmt.Errorf("branch '%s' does not exist", branch)
+	case TempExtraCommits:
+		return nil, fmt.Errorf("temp branch '%s' has diverged from '%s' (%d commit(s) not in '%s')\n  Revert and detach again instead", tmpBranch, branch, n, branch)
+	case TempIdentical:
+		result.UpToDate = true
+		return result, nil
+	}
+
+	if result.From, err = d.git.Run("rev-parse", "refs/heads/"+tmpBranch); err != nil {
+		return nil, fmt.Errorf("failed to resolve '%s': %w", tmpBranch, err)
+	}
+	if result.To, err = d.git.Run("rev-parse", "refs/heads/"+branch); err != nil {
+		return nil, fmt.Errorf("failed to resolve '%s': %w", branch, err)
+	}
+
+	wt, err := d.FindWorktreeForTempBranch(tmpBranch)
+	if err != nil {
+		return nil, err
+	}
+	if wt != nil {
+		result.WorktreePath = wt.Path
+		if d.HasUncommittedChanges(wt.Path) && !opts.Force {
+			return nil, fmt.Errorf("uncommitted changes found in worktree: %s\n  Use --force to override", wt.Path)
+		}
+	}
+
+	if opts.DryRun {
+		return result, nil
+	}
+
+	if wt != nil {
+		// Moves the branch and the working tree together, carrying any
+		// uncommitted changes that do not conflict
+		if _, err := d.git.RunInDir(wt.Path, "merge", "--ff-only", "--quiet", result.To); err != nil {
+			return nil, fmt.Errorf("failed to fast-forward worktree %s: %w", wt.Path, err)
+		}
+		return result, nil
+	}
+
+	if _, err := d.git.Run("update-ref", "refs/heads/"+tmpBranch, result.To, result.From); err != nil {
+		return nil, fmt.Errorf("failed to fast-forward '%s': %w", tmpBranch, err)
+	}
+	return result, nil
+}
@@ -0,0 +1,92 @@
+package wtdetach
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIntegration_Refresh(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature-refresh")
+	worktreeDir := filepath.Join(resolvePath(t, t.TempDir()), "worktree-refresh")
+	createWorktree(t, repoDir, worktreeDir, "feature-refresh")
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+	if _, err := d.Detach("feature-refresh", &Options{Yes: true, Checkout: true}); err != nil {
+		t.Fatalf("Detach failed: %v", err)
+	}
+
+	// Test: nothing to do right after detaching
+	result, err := d.Refresh("feature-refresh", &Options{})
+	if err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if !result.UpToDate {
+		t.Error("temp branch should be up to date")
+	}
+
+	// The borrowed branch moves on in the current worktree
+	os.WriteFile(filepath.Join(repoDir, "new.txt"), []byte("new\n"), 0644)
+	runGit(t, repoDir, "add", "new.txt")
+	runGit(t, repoDir, "commit", "-m", "add new.txt")
+
+	// Test: a dirty worktree is not refreshed
+	createUncommittedChange(t, worktreeDir)
+	if _, err := d.Refresh("feature-refresh", &Options{}); err == nil {
+		t.Error("Refresh should fail with uncommitted changes in the worktree")
+	}
+	os.Remove(filepath.Join(worktreeDir, "uncommitted.txt"))
+
+	result, err = d.Refresh("feature-refresh", &Options{})
+	if err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if result.UpToDate || result.WorktreePath != worktreeDir {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(worktreeDir, "new.txt")); err != nil {
+		t.Error("worktree should be fast-forwarded to the new commit")
+	}
+	if branch := getCurrentBranch(t, worktreeDir); branch != "feature-refresh__wt_detach" {
+		t.Errorf("worktree should stay on the temp branch, got %s", branch)
+	}
+
+	// Test: a diverged temp branch is refused
+	runGit(t, worktreeDir, "commit", "--allow-empty", "-m", "diverge")
+	runGit(t, repoDir, "commit", "--allow-empty", "-m", "move on")
+	_, err = d.Refresh("feature-refresh", &Options{})
+	if err == nil || !strings.Contains(err.Error(), "diverged") {
+		t.Errorf("expected a divergence error, got %v", err)
+	}
+}
+
+func TestIntegration_RefreshWithoutWorktree(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature-loose")
+	runGit(t, repoDir, "branch", "feature-loose__wt_detach", "feature-loose")
+	runGit(t, repoDir, "config", "branch.feature-loose__wt_detach.wtDetachOrigin", "feature-loose")
+	runGit(t, repoDir, "checkout", "feature-loose")
+	runGit(t, repoDir, "commit", "--allow-empty", "-m", "move on")
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+	result, err := d.Refresh("feature-loose", &Options{})
+	if err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if result.WorktreePath != "" {
+		t.Errorf("expected no worktree, got %s", result.WorktreePath)
+	}
+	if status, _ := d.ClassifyTempBranch("feature-loose__wt_detach", "feature-loose"); status != TempIdentical {
+		t.Errorf("temp branch should point at the original, got %s", status)
+	}
+}
@@ -0,0 +1,157 @@
+package wtdetach
+
+import (
+	"fmt"
+	"strings"
+)
+
+// snapshotRefPrefix is where snapshots of worktree changes are kept, out of
+// the way of branches and the stash
+const snapshotRefPrefix = "refs/wt-detach/snapshots/"
+
+// SnapshotRef returns the private ref holding the snapshot taken for a
+// temporary branch
+func SnapshotRef(tmpBranch string) string {
+	return snapshotRefPrefix + tmpBranch
+}
+
+// SnapshotConflict reports changes that could not be restored after a
+// checkout. They are kept at Ref.
+type SnapshotConflict struct {
+	Path  string
+	Ref   string
+	Files []string
+}
+
+func (c *SnapshotConflict) Error() string {
+	msg := fmt.Sprintf("uncommitted changes in %s could not be restored", c.Path)
+	if len(c.Files) > 0 {
+		msg += "; they conflict in:\n    - " + strings.Join(c.Files, "\n    - ")
+	}
+	return msg + fmt.Sprintf("\n  They are kept at %s. Apply them with:\n    git -C %s stash apply --index %s\n  and drop them afterwards with:\n    git update-ref -d %s", c.Ref, c.Path, c.Ref, c.Ref)
+}
+
+// Snapshot records the staged and unstaged changes to tracked files in a
+// worktree as a stash-like commit at ref, without touching the worktree.
+// It returns the commit, or empty if there are no changes.
+func (d *Detacher) Snapshot(worktreePath, ref string) (string, error) {
+	commit, err := d.git.RunInDir(worktreePath, "stash", "create", "wt-detach snapshot")
+	if err != nil {
+		return "", fmt.Errorf("failed to snapshot changes in %s: %w", worktreePath, err)
+	}
+	if commit == "" {
+		return "", nil
+	}
+	if _, err := d.git.Run("update-ref", "-m", "wt-detach: snapshot of "+worktreePath, ref, commit); err != nil {
+		return "", fmt.Errorf("failed to record snapshot at %s: %w", ref, err)
+	}
+	return commit, nil
+}
+
+// SnapshotExists reports whether a snapshot ref exists
+func (d *Detacher) SnapshotExists(ref string) bool {
+	_, err := d.git.Run("rev-parse", "--verify", "--quiet", ref)
+	return err == nil
+}
+
+// DeleteSnapshot removes a snapshot ref, if it exists. An empty ref, for no
+// snapshot taken, is ignored.
+func (d *Detacher) DeleteSnapshot(ref string) {
+	if ref == "" {
+		return
+	}
+	d.git.Run("update-ref", "-d", ref)
+}
+
+// checkoutWithSnapshot checks out branch in a worktree and restores its
+// staged and unstaged changes exactly, which a plain checkout may mix up or
+// refuse to carry. If the changes do not apply to the new branch, the
+// checkout is kept, the changes are left at ref, and a *SnapshotConflict is
+// returned. Otherwise ref is removed.
+func (d *Detacher) checkoutWithSnapshot(worktreePath, branch, ref string) error {
+	commit, err := d.Snapshot(worktreePath, ref)
+	if err != nil {
+		return err
+	}
+	if commit == "" {
+		if err := d.Checkout(worktreePath, branch); err != nil {
+			return err
+		}
+		d.DeleteSnapshot(ref)
+		return nil
+	}
+
+	if _, err := d.git.RunInDir(worktreePath, "reset", "--hard", "--quiet"); err != nil {
+		return fmt.Errorf("failed to set aside changes in %s: %w", worktreePath, err)
+	}
+	if err := d.Checkout(worktreePath, branch); err != nil {
+		// The snapshot is based on the commit still checked out
+		d.git.RunInDir(worktreePath, "stash", "apply", "--index", commit)
+		return err
+	}
+
+	if _, err := d.git.RunInDir(worktreePath, "stash", "apply", "--index", commit); err != nil {
+		// A failed apply may leave a partial result behind
+		d.git.RunInDir(worktreePath, "reset", "--hard", "--quiet")
+		return &SnapshotConflict{
+			Path:  worktreePath,
+			Ref:   ref,
+			Files: d.snapshotConflicts(commit, branch),
+		}
+	}
+	d.DeleteSnapshot(ref)
+	return nil
+}
+
+// snapshotConflicts returns the files changed both by a snapshot and between
+// the commit it was taken on and branch
+func (d *Detacher) snapshotConflicts(commit, branch string) []string {
+	changed, err := d.git.Run("diff", "--name-only", "-z", commit+"^1", commit)
+	if err != nil {
+		return nil
+	}
+	staged, _ := d.git.Run("diff", "--name-only", "-z", commit+"^1", commit+"^2")
+	moved, err := d.git.Run("diff", "--name-only", "-z", commit+"^1", "refs/heads/"+branch)
+	if err != nil {
+		return nil
+	}
+
+	inSnapshot := map[string]bool{}
+	for _, path := range strings.Split(changed+"\x00"+staged, "\x00") {
+		inSnapshot[path] = path != ""
+	}
+	var files []string
+	for _, path := range strings.Split(moved, "\x00") {
+		if inSnapshot[path] {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// StaleSnapshot is a snapshot whose temporary branch no longer exists. It
+// holds changes that revert could not restore, or was left behind by a
+// command that did not finish.
+type StaleSnapshot struct {
+	Ref        string
+	TempBranch string
+	Deleted    bool
+}
+
+// FindStaleSnapshots returns the snapshots whose temporary branch no longer
+// exists
+func (d *Detacher) FindStaleSnapshots() ([]*StaleSnapshot, error) {
+	output, err := d.git.Run("for-each-ref", "--format=%(refname)", snapshotRefPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	var stale []*StaleSnapshot
+	for _, ref := range splitLines(output) {
+		tmpBranch := strings.TrimPrefix(ref, snapshotRefPrefix)
+		if !d.BranchExists(tmpBranch) {
+			stale = append(stale, &StaleSnapshot{Ref: ref, TempBranch: tmpBranch})
+		}
+	}
+	return stale, nil
+}
@@ -0,0 +1,209 @@
+package wtdetach
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func refExists(t *testing.T, dir, ref string) bool {
+	t.Helper()
+	cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", ref)
+	cmd.Dir = dir
+	return cmd.Run() == nil
+}
+
+func TestIntegration_ForceKeepsStagedSplit(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature-split")
+	worktreeDir := filepath.Join(resolvePath(t, t.TempDir()), "worktree-split")
+	createWorktree(t, repoDir, worktreeDir, "feature-split")
+
+	// Staged and unstaged changes to the same file
+	readme := filepath.Join(worktreeDir, "README.md")
+	os.WriteFile(readme, []byte("# Test\nstaged\n"), 0644)
+	runGit(t, worktreeDir, "add", "README.md")
+	os.WriteFile(readme, []byte("# Test\nstaged\nunstaged\n"), 0644)
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+	ref := SnapshotRef("feature-split__wt_detach")
+
+	result, err := d.Detach("feature-split", &Options{Yes: true, Force: true})
+	if err != nil {
+		t.Fatalf("Detach failed: %v", err)
+	}
+	if result.Snapshot != ref || !refExists(t, repoDir, ref) {
+		t.Errorf("expected a snapshot at %s, got %q", ref, result.Snapshot)
+	}
+
+	revert, err := d.Revert("feature-split", &Options{Yes: true, Force: true})
+	if err != nil {
+		t.Fatalf("Revert failed: %v", err)
+	}
+	if revert.Conflict != nil {
+		t.Fatalf("unexpected conflict: %v", revert.Conflict)
+	}
+
+	if staged := runGit(t, worktreeDir, "show", ":README.md"); staged != "# Test\nstaged" {
+		t.Errorf("index should keep the staged change, got %q", staged)
+	}
+	if content, _ := os.ReadFile(readme); string(content) != "# Test\nstaged\nunstaged\n" {
+		t.Errorf("working tree should keep the unstaged change, got %q", content)
+	}
+	if refExists(t, repoDir, ref) {
+		t.Error("snapshot should be deleted after a clean restore")
+	}
+}
+
+func TestIntegration_ForceRevertSnapshotConflict(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature-clash")
+	worktreeDir := filepath.Join(resolvePath(t, t.TempDir()), "worktree-clash")
+	createWorktree(t, repoDir, worktreeDir, "feature-clash")
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+	if _, err := d.Detach("feature-clash", &Options{Yes: true, Checkout: true}); err != nil {
+		t.Fatalf("Detach failed: %v", err)
+	}
+
+	// The borrowed branch and the worktree change the same line
+	os.WriteFile(filepath.Join(repoDir, "README.md"), []byte("# Borrowed\n"), 0644)
+	runGit(t, repoDir, "commit", "-am", "change README")
+	os.WriteFile(filepath.Join(worktreeDir, "README.md"), []byte("# Local\n"), 0644)
+	runGit(t, repoDir, "checkout", "main")
+
+	result, err := d.Revert("feature-clash", &Options{Yes: true, Force: true})
+	if err != nil {
+		t.Fatalf("Revert failed: %v", err)
+	}
+
+	conflict := result.Conflict
+	if conflict == nil {
+		t.Fatal("expected a snapshot conflict")
+	}
+	if !slices.Contains(conflict.Files, "README.md") {
+		t.Errorf("conflict should list README.md, got %v", conflict.Files)
+	}
+	if branch := getCurrentBranch(t, worktreeDir); branch != "feature-clash" {
+		t.Errorf("worktree should be back on feature-clash, got %s", branch)
+	}
+	if !refExists(t, repoDir, conflict.Ref) {
+		t.Error("snapshot should be kept when it cannot be restored")
+	}
+	if content := runGit(t, repoDir, "show", conflict.Ref+":README.md"); content != "# Local" {
+		t.Errorf("snapshot should hold the local change, got %q", content)
+	}
+	kept := runGit(t, repoDir, "rev-parse", conflict.Ref)
+
+	// Test: the kept changes are not overwritten or dropped by the next
+	// detach, whether the worktree is clean or not
+	for _, force := range []bool{false, true} {
+		if force {
+			createUncommittedChange(t, worktreeDir)
+			runGit(t, worktreeDir, "add", "uncommitted.txt")
+		}
+		if _, err := d.Detach("feature-clash", &Options{Yes: true, Force: force}); err == nil || !strings.Contains(err.Error(), conflict.Ref) {
+			t.Fatalf("Detach should refuse while changes are kept at %s, got %v", conflict.Ref, err)
+		}
+		if got := runGit(t, repoDir, "rev-parse", conflict.Ref); got != kept {
+			t.Fatalf("kept changes should be left alone, got %s", got)
+		}
+	}
+	if branchExistsInRepo(t, repoDir, "feature-clash__wt_detach") {
+		t.Error("temp branch should not be created")
+	}
+
+	// Once they are dropped, the branch can be detached and reverted again
+	runGit(t, repoDir, "update-ref", "-d", conflict.Ref)
+	if _, err := d.Detach("feature-clash", &Options{Yes: true, Force: true}); err != nil {
+		t.Fatalf("Detach failed: %v", err)
+	}
+	if result, err = d.Revert("feature-clash", &Options{Yes: true, Force: true}); err != nil || result.Conflict != nil {
+		t.Fatalf("Revert failed: %v, %v", err, result.Conflict)
+	}
+	if refExists(t, repoDir, conflict.Ref) {
+		t.Error("snapshot should be deleted after a clean restore")
+	}
+}
+
+func TestIntegration_SnapshotDeletedWithTempBranch(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature-moved")
+	worktreeDir := filepath.Join(resolvePath(t, t.TempDir()), "worktree-moved")
+	createWorktree(t, repoDir, worktreeDir, "feature-moved")
+	createUncommittedChange(t, worktreeDir)
+	runGit(t, worktreeDir, "add", "uncommitted.txt")
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+	ref := SnapshotRef("feature-moved__wt_detach")
+	if _, err := d.Detach("feature-moved", &Options{Yes: true, Force: true}); err != nil {
+		t.Fatalf("Detach failed: %v", err)
+	}
+
+	// The worktree leaves the temp branch on its own
+	runGit(t, worktreeDir, "checkout", "--detach")
+	if _, err := d.Revert("feature-moved", &Options{Yes: true}); err != nil {
+		t.Fatalf("Revert failed: %v", err)
+	}
+	if refExists(t, repoDir, ref) {
+		t.Error("snapshot should be deleted with the temp branch")
+	}
+}
+
+func TestIntegration_GCSnapshots(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature-stale")
+	worktreeDir := filepath.Join(resolvePath(t, t.TempDir()), "worktree-stale")
+	createWorktree(t, repoDir, worktreeDir, "feature-stale")
+	createUncommittedChange(t, worktreeDir)
+	runGit(t, worktreeDir, "add", "uncommitted.txt")
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+	if _, err := d.Detach("feature-stale", &Options{Yes: true, Force: true}); err != nil {
+		t.Fatalf("Detach failed: %v", err)
+	}
+
+	// The snapshot of a temp branch that still exists is not stale
+	stale, err := d.GCSnapshots(&Options{Force: true})
+	if err != nil || len(stale) != 0 {
+		t.Fatalf("expected no stale snapshots, got %v, %v", stale, err)
+	}
+
+	// Left behind as if by an interrupted command
+	ref := SnapshotRef("feature-stale__wt_detach")
+	runGit(t, repoDir, "update-ref", SnapshotRef("feature-gone__wt_detach"), ref)
+
+	stale, err = d.GCSnapshots(&Options{})
+	if err != nil || len(stale) != 1 || stale[0].TempBranch != "feature-gone__wt_detach" {
+		t.Fatalf("expected the snapshot of feature-gone__wt_detach, got %v, %v", stale, err)
+	}
+	if stale[0].Deleted || !refExists(t, repoDir, stale[0].Ref) {
+		t.Error("stale snapshot should be kept without --force")
+	}
+
+	if stale, _ = d.GCSnapshots(&Options{Force: true}); len(stale) != 1 || !stale[0].Deleted {
+		t.Fatalf("expected the stale snapshot to be deleted, got %v", stale)
+	}
+	if refExists(t, repoDir, stale[0].Ref) || !refExists(t, repoDir, ref) {
+		t.Error("only the stale snapshot should be deleted")
+	}
+}
@@ -0,0 +1,181 @@
+package wtdetach
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// stateKeyOrigin is the name of the branch that was detached
+	stateKeyOrigin = "wtDetachOrigin"
+	// stateKeyWorktree is the path of the worktree switched to the temp branch
+	stateKeyWorktree = "wtDetachWorktree"
+	// stateKeyLocked marks that the target worktree was locked by wt-detach
+	stateKeyLocked = "wtDetachLocked"
+	// stateKeyCheckoutWorktree is the path of the worktree the branch was checked out in
+	stateKeyCheckoutWorktree = "wtDetachCheckoutWorktree"
+	// stateKeyPrevBranch is the branch the checkout worktree was on before
+	stateKeyPrevBranch = "wtDetachPrevBranch"
+	// stateKeyPrevHead is the commit the checkout worktree was on before
+	stateKeyPrevHead = "wtDetachPrevHead"
+	// stateKeyExpires is the time after which the detach should be reverted
+	stateKeyExpires = "wtDetachExpires"
+)
+
+// State holds the metadata recorded on a temporary branch at detach time.
+// It is stored in the branch's git config section (branch.<tmp>.wtDetach*),
+// so it is removed together with the temporary branch.
+type State struct {
+	TempBranch string
+	Origin     string
+	Worktree   string
+	Locked     bool
+
+	// CheckoutWorktree is the worktree the original branch was checked out in
+	// after detaching, and PrevBranch/PrevHead what it had checked out before
+	CheckoutWorktree string
+	PrevBranch       string
+	PrevHead         string
+
+	// Expires is the time after which the detach should be reverted, zero
+	// if it does not expire
+	Expires time.Time
+}
+
+// fields returns the config keys and values of the state
+func (st *State) fields() []ConfigEntry {
+	locked := ""
+	if st.Locked {
+		locked = "true"
+	}
+	expires := ""
+	if !st.Expires.IsZero() {
+		expires = st.Expires.Format(time.RFC3339)
+	}
+	return []ConfigEntry{
+		{Key: stateKeyOrigin, Value: st.Origin},
+		{Key: stateKeyWorktree, Value: st.Worktree},
+		{Key: stateKeyLocked, Value: locked},
+		{Key: stateKeyCheckoutWorktree, Value: st.CheckoutWorktree},
+		{Key: stateKeyPrevBranch, Value: st.PrevBranch},
+		{Key: stateKeyPrevHead, Value: st.PrevHead},
+		{Key: stateKeyExpires, Value: expires},
+	}
+}
+
+// LoadState reads the metadata recorded on a temporary branch
+func (d *Detacher) LoadState(tmpBranch string) *State {
+	values := map[string]string{}
+	prefix := "branch." + tmpBranch + "."
+	output, err := d.git.Run("config", "-z", "--get-regexp", "^"+regexp.QuoteMeta(prefix)+"wtdetach")
+	if err == nil {
+		for _, entry := range ParseConfigEntries(output) {
+			values[strings.ToLower(strings.TrimPrefix(entry.Key, prefix))] = entry.Value
+		}
+	}
+	get := func(key string) string {
+		return values[strings.ToLower(key)]
+	}
+
+	// A malformed deadline is treated as none
+	expires, _ := time.Parse(time.RFC3339, get(stateKeyExpires))
+
+	return &State{
+		TempBranch:       tmpBranch,
+		Origin:           get(stateKeyOrigin),
+		Worktree:         get(stateKeyWorktree),
+		Locked:           get(stateKeyLocked) == "true",
+		CheckoutWorktree: get(stateKeyCheckoutWorktree),
+		PrevBranch:       get(stateKeyPrevBranch),
+		PrevHead:         get(stateKeyPrevHead),
+		Expires:          expires,
+	}
+}
+
+// SaveState records the metadata on a temporary branch.
+// Empty fields are removed from the config.
+func (d *Detacher) SaveState(st *State) error {
+	for _, f := range st.fields() {
+		if f.Value == "" {
+			d.unsetBranchConfig(st.TempBranch, f.Key)
+			continue
+		}
+		if err := d.setBranchConfig(st.TempBranch, f.Key, f.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClearState removes the wt-detach metadata and the disabled push remote
+// from a branch, e.g. after a temp branch took over its original's name
+func (d *Detacher) ClearState(branch string) {
+	for _, f := range (&State{}).fields() {
+		d.unsetBranchConfig(branch, f.Key)
+	}
+	if d.getBranchConfig(branch, "pushRemote") == DisabledPushRemote {
+		d.unsetBranchConfig(branch, "pushRemote")
+	}
+}
+
+// ListStates returns the metadata of all existing temporary branches
+// recorded by wt-detach
+func (d *Detacher) ListStates() ([]*State, error) {
+	output, err := d.git.Run("config", "-z", "--get-regexp", `^branch\..*\.wtdetachorigin$`)
+	if err != nil {
+		// git config exits with 1 when nothing matches
+		return nil, nil
+	}
+
+	var states []*State
+	for _, entry := range ParseConfigEntries(output) {
+		tmpBranch := strings.TrimPrefix(entry.Key, "branch.")
+		tmpBranch = tmpBranch[:strings.LastIndex(tmpBranch, ".")]
+		if !d.BranchExists(tmpBranch) {
+			continue
+		}
+		states = append(states, d.LoadState(tmpBranch))
+	}
+	return states, nil
+}
+
+// ConfigEntry is a key/value pair of git config
+type ConfigEntry struct {
+	Key   string
+	Value string
+}
+
+// ParseConfigEntries parses the output of `git config -z --get-regexp`
+func ParseConfigEntries(output string) []ConfigEntry {
+	var entries []ConfigEntry
+	for _, record := range strings.Split(output, "\x00") {
+		if record == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(record, "\n")
+		entries = append(entries, ConfigEntry{Key: key, Value: value})
+	}
+	return entries
+}
+
+func (d *Detacher) getBranchConfig(branch, key string) string {
+	value, err := d.git.Run("config", "--get", "branch."+branch+"."+key)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(value)
+}
+
+func (d *Detacher) setBranchConfig(branch, key, value string) error {
+	if _, err := d.git.Run("config", "branch."+branch+"."+key, value); err != nil {
+		return fmt.Errorf("failed to record %s on '%s': %w", key, branch, err)
+	}
+	return nil
+}
+
+func (d *Detacher) unsetBranchConfig(branch, key string) {
+	// git config exits with 5 when the key does not exist
+	d.git.Run("config", "--unset", "branch."+branch+"."+key)
+}
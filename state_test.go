@@ -0,0 +1,24 @@
+package wtdetach
+
+import (
+	"testing"
+)
+
+func TestParseConfigEntries(t *testing.T) {
+	input := "branch.feature.x__wt_detach.wtdetachorigin\nfeature.x\x00branch.a__wt_detach.wtdetachworktree\n/path/with space\x00"
+
+	entries := ParseConfigEntries(input)
+	expected := []ConfigEntry{
+		{Key: "branch.feature.x__wt_detach.wtdetachorigin", Value: "feature.x"},
+		{Key: "branch.a__wt_detach.wtdetachworktree", Value: "/path/with space"},
+	}
+
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d entries, got %d: %+v", len(expected), len(entries), entries)
+	}
+	for i, e := range entries {
+		if e != expected[i] {
+			t.Errorf("entry[%d]: expected %+v, got %+v", i, expected[i], e)
+		}
+	}
+}
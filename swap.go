@@ -0,0 +1,114 @@
+package wtdetach
+
+import (
+	"fmt"
+)
+
+// SwapPlan describes the worktrees whose branches are exchanged by Swap
+type SwapPlan struct {
+	CurrentPath   string
+	CurrentBranch string
+	OtherPath     string
+	OtherBranch   string
+}
+
+// PlanSwap resolves the other worktree of a swap, given either the branch it
+// has checked out or its path or basename
+func (d *Detacher) PlanSwap(spec string) (*SwapPlan, error) {
+	currentPath, err := d.GetCurrentWorktreePath()
+	if err != nil {
+		return nil, err
+	}
+	currentBranch, _, err := d.CurrentHead(currentPath)
+	if err != nil {
+		return nil, err
+	}
+	if currentBranch == "" {
+		return nil, fmt.Errorf("current worktree is not on a branch (HEAD is detached)")
+	}
+
+	other, err := d.FindWorktreeForBranch(spec)
+	if err != nil {
+		return nil, err
+	}
+	if other == nil {
+		if other, err = d.ResolveWorktree(spec); err != nil {
+			return nil, fmt.Errorf("'%s' is neither a branch checked out in another worktree nor a worktree", spec)
+		}
+	}
+	if other.Path == currentPath {
+		return nil, fmt.Errorf("cannot swap the current worktree with itself")
+	}
+	if other.Branch == "" {
+		return nil, fmt.Errorf("worktree %s is not on a branch (HEAD is detached)", other.Path)
+	}
+
+	return &SwapPlan{
+		CurrentPath:   currentPath,
+		CurrentBranch: currentBranch,
+		OtherPath:     other.Path,
+		OtherBranch:   other.Branch,
+	}, nil
+}
+
+// Swap exchanges the branches of the current worktree and another one.
+// The other worktree is moved to a detached HEAD in between, and every
+// checkout is rolled back if a later one fails.
+func (d *Detacher) Swap(spec string, opts *Options) (*SwapPlan, error) {
+	plan, err := d.PlanSwap(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range []string{plan.CurrentPath, plan.OtherPath} {
+		if d.HasUncommittedChanges(path) && !opts.Force {
+			return nil, fmt.Errorf("uncommitted changes found in worktree: %s\n  Use --force to override", path)
+		}
+	}
+
+	if opts.DryRun {
+		return plan, nil
+	}
+
+	if err := d.CheckoutDetached(plan.OtherPath, "HEAD"); err != nil {
+		return nil, err
+	}
+
+	if err := d.Checkout(plan.CurrentPath, plan.OtherBranch); err != nil {
+		return nil, fmt.Errorf("%w\n  %s", err, d.switchBack(plan.OtherPath, plan.OtherBranch, ""))
+	}
+
+	if err := d.Checkout(plan.OtherPath, plan.CurrentBranch); err != nil {
+		return nil, fmt.Errorf("%w\n  %s\n  %s", err,
+			d.switchBack(plan.CurrentPath, plan.CurrentBranch, ""),
+			d.switchBack(plan.OtherPath, plan.OtherBranch, ""))
+	}
+
+	return plan, nil
+}
+
+// switchBack undoes a checkout in a worktree after a later one failed,
+// checking out branch, or commit on a detached HEAD if branch is empty. It
+// describes the state the worktree is left in.
+func (d *Detacher) switchBack(path, branch, commit string) string {
+	var err error
+	target := fmt.Sprintf("'%s'", branch)
+	if branch != "" {
+		err = d.Checkout(path, branch)
+	} else {
+		target = "detached HEAD at " + shortHash(commit)
+		err = d.CheckoutDetached(path, commit)
+	}
+	if err == nil {
+		return fmt.Sprintf("Worktree %s was switched back to %s", path, target)
+	}
+
+	current := "an unknown commit"
+	if branch, head, herr := d.CurrentHead(path); herr == nil {
+		current = "detached HEAD at " + shortHash(head)
+		if branch != "" {
+			current = fmt.Sprintf("'%s'", branch)
+		}
+	}
+	return fmt.Sprintf("Worktree %s could not be switched back to %s and is left on %s: %v", path, target, current, err)
+}
@@ -0,0 +1,104 @@
+package wtdetach
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIntegration_Swap(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature-swap")
+	worktreeDir := filepath.Join(resolvePath(t, t.TempDir()), "worktree-swap")
+	createWorktree(t, repoDir, worktreeDir, "feature-swap")
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+
+	// Test: swap by branch name
+	plan, err := d.Swap("feature-swap", &Options{Yes: true})
+	if err != nil {
+		t.Fatalf("Swap failed: %v", err)
+	}
+	if plan.OtherPath != worktreeDir {
+		t.Errorf("OtherPath: expected %s, got %s", worktreeDir, plan.OtherPath)
+	}
+	if branch := getCurrentBranch(t, repoDir); branch != "feature-swap" {
+		t.Errorf("current worktree should be on feature-swap, got %s", branch)
+	}
+	if branch := getCurrentBranch(t, worktreeDir); branch != "main" {
+		t.Errorf("other worktree should be on main, got %s", branch)
+	}
+
+	// Test: swap back by worktree name
+	if _, err := d.Swap("worktree-swap", &Options{Yes: true}); err != nil {
+		t.Fatalf("Swap by worktree name failed: %v", err)
+	}
+	if branch := getCurrentBranch(t, repoDir); branch != "main" {
+		t.Errorf("current worktree should be on main, got %s", branch)
+	}
+	if branch := getCurrentBranch(t, worktreeDir); branch != "feature-swap" {
+		t.Errorf("other worktree should be on feature-swap, got %s", branch)
+	}
+
+	// Test: swap refuses dirty worktrees
+	createUncommittedChange(t, worktreeDir)
+	if _, err := d.Swap("feature-swap", &Options{Yes: true}); err == nil {
+		t.Error("Swap should fail with uncommitted changes")
+	}
+}
+
+func TestIntegration_SwapRollback(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	createBranch(t, repoDir, "feature-rollback")
+
+	// main gets a file that collides with an untracked file in the other worktree
+	if err := os.WriteFile(filepath.Join(repoDir, "conflict.txt"), []byte("main\n"), 0644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	runGit(t, repoDir, "add", "conflict.txt")
+	runGit(t, repoDir, "commit", "-m", "add conflict.txt")
+
+	worktreeDir := filepath.Join(resolvePath(t, t.TempDir()), "worktree-rollback")
+	createWorktree(t, repoDir, worktreeDir, "feature-rollback")
+	if err := os.WriteFile(filepath.Join(worktreeDir, "conflict.txt"), []byte("untracked\n"), 0644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoDir)
+	defer os.Chdir(oldWd)
+
+	d := NewDetacher()
+
+	_, err := d.Swap("feature-rollback", &Options{Yes: true, Force: true})
+	if err == nil {
+		t.Fatal("Swap should fail when the other worktree cannot checkout main")
+	}
+	for _, want := range []string{
+		"Worktree " + repoDir + " was switched back to 'main'",
+		"Worktree " + worktreeDir + " was switched back to 'feature-rollback'",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error should report %q, got: %v", want, err)
+		}
+	}
+
+	// Verify: both worktrees are back on their branches
+	if branch := getCurrentBranch(t, repoDir); branch != "main" {
+		t.Errorf("current worktree should be back on main, got %s", branch)
+	}
+	if branch := getCurrentBranch(t, worktreeDir); branch != "feature-rollback" {
+		t.Errorf("other worktree should be back on feature-rollback, got %s", branch)
+	}
+
+	// Test: a worktree that cannot be switched back is reported where it is
+	msg := d.switchBack(worktreeDir, "no-such-branch", "")
+	if want := "could not be switched back to 'no-such-branch' and is left on 'feature-rollback'"; !strings.Contains(msg, want) {
+		t.Errorf("expected %q, got %q", want, msg)
+	}
+}
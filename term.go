@@ -0,0 +1,17 @@
+//go:build linux || darwin
+
+package wtdetach
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// isTerminal reports whether f is a terminal, by asking for its terminal
+// attributes like isatty(3) does
+func isTerminal(f *os.File) bool {
+	var termios syscall.Termios
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), ioctlGetTermios, uintptr(unsafe.Pointer(&termios)))
+	return errno == 0
+}
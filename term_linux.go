@@ -0,0 +1,5 @@
+package wtdetach
+
+import "syscall"
+
+const ioctlGetTermios = syscall.TCGETS
@@ -0,0 +1,11 @@
+//go:build !linux && !darwin
+
+package wtdetach
+
+import "os"
+
+// isTerminal reports whether f is a terminal. Prompts are not supported on
+// this platform, so it never is.
+func isTerminal(f *os.File) bool {
+	return false
+}
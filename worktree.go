@@ -2,13 +2,20 @@ package wtdetach
 
 import (
 	"bufio"
+	"path/filepath"
 	"strings"
 )
 
 // Worktree represents a git worktree
 type Worktree struct {
-	Path   string
-	Branch string
+	Path       string
+	Branch     string
+	Main       bool
+	Locked     bool
+	LockReason string
+	Prunable   bool
+	Bare       bool
+	Detached   bool
 }
 
 // ParseWorktreeList parses the output of `git worktree list --porcelain`
@@ -22,11 +29,30 @@ func ParseWorktreeList(output string) []Worktree {
 		if strings.HasPrefix(line, "worktree ") {
 			current = &Worktree{
 				Path: strings.TrimPrefix(line, "worktree "),
+				// The main worktree is always listed first
+				Main: len(worktrees) == 0,
 			}
 		} else if strings.HasPrefix(line, "branch refs/heads/") {
 			if current != nil {
 				current.Branch = strings.TrimPrefix(line, "branch refs/heads/")
 			}
+		} else if line == "locked" || strings.HasPrefix(line, "locked ") {
+			if current != nil {
+				current.Locked = true
+				current.LockReason = strings.TrimPrefix(strings.TrimPrefix(line, "locked"), " ")
+			}
+		} else if line == "bare" {
+			if current != nil {
+				current.Bare = true
+			}
+		} else if line == "detached" {
+			if current != nil {
+				current.Detached = true
+			}
+		} else if line == "prunable" || strings.HasPrefix(line, "prunable ") {
+			if current != nil {
+				current.Prunable = true
+			}
 		} else if line == "" {
 			if current != nil {
 				worktrees = append(worktrees, *current)
@@ -53,3 +79,25 @@ func FindWorktreeByBranch(worktrees []Worktree, branch, excludePath string) *Wor
 	}
 	return nil
 }
+
+// MatchWorktrees returns the worktrees whose path equals path, or whose
+// path or basename matches path or name as a glob pattern
+func MatchWorktrees(worktrees []Worktree, path, name string) []Worktree {
+	var matches []Worktree
+	for _, wt := range worktrees {
+		if wt.Path == path {
+			return []Worktree{wt}
+		}
+		if filepath.Base(wt.Path) == name || globMatch(path, wt.Path) || globMatch(name, filepath.Base(wt.Path)) {
+			matches = append(matches, wt)
+		}
+	}
+	return matches
+}
+
+// globMatch reports whether s matches the glob pattern, treating a malformed
+// pattern as no match
+func globMatch(pattern, s string) bool {
+	ok, err := filepath.Match(pattern, s)
+	return err == nil && ok
+}
@@ -60,7 +60,62 @@ detached
 `,
 			expected: []Worktree{
 				{Path: "/path/to/repo", Branch: "main"},
-				{Path: "/path/to/detached", Branch: ""},
+				{Path: "/path/to/detached", Branch: "", Detached: true},
+			},
+		},
+		{
+			name: "bare repository",
+			input: `worktree /path/to/repo.git
+bare
+
+worktree /path/to/worktree1
+HEAD def456
+branch refs/heads/feature-x
+
+`,
+			expected: []Worktree{
+				{Path: "/path/to/repo.git", Main: true, Bare: true},
+				{Path: "/path/to/worktree1", Branch: "feature-x"},
+			},
+		},
+		{
+			name: "locked worktrees",
+			input: `worktree /path/to/repo
+HEAD abc123
+branch refs/heads/main
+
+worktree /path/to/locked
+HEAD def456
+branch refs/heads/feature-x
+locked
+
+worktree /path/to/locked-with-reason
+HEAD 789ghi
+branch refs/heads/feature-y
+locked wt-detach: feature-y borrowed from here
+
+`,
+			expected: []Worktree{
+				{Path: "/path/to/repo", Branch: "main", Main: true},
+				{Path: "/path/to/locked", Branch: "feature-x", Locked: true},
+				{Path: "/path/to/locked-with-reason", Branch: "feature-y", Locked: true, LockReason: "wt-detach: feature-y borrowed from here"},
+			},
+		},
+		{
+			name: "prunable worktree",
+			input: `worktree /path/to/repo
+HEAD abc123
+branch refs/heads/main
+
+worktree /path/to/gone
+HEAD def456
+branch refs/heads/feature-x
+prunable gitdir file points to non-existent location
+
+`,
+			expected: []Worktree{
+				{Path: "/path/to/repo", Branch: "main", Main: true},
+				{Path: "/path/to/gone", Branch: "feature-x", Prunable: true},
 			},
 		},
 		{
@@ -88,6 +143,21 @@ branch refs/heads/main`,
 				if wt.Branch != tt.expected[i].Branch {
 					t.Errorf("worktree[%d].Branch: expected %q, got %q", i, tt.expected[i].Branch, wt.Branch)
 				}
+				if wt.Locked != tt.expected[i].Locked {
+					t.Errorf("worktree[%d].Locked: expected %v, got %v", i, tt.expected[i].Locked, wt.Locked)
+				}
+				if wt.LockReason != tt.expected[i].LockReason {
+					t.Errorf("worktree[%d].LockReason: expected %q, got %q", i, tt.expected[i].LockReason, wt.LockReason)
+				}
+				if wt.Prunable != tt.expected[i].Prunable {
+					t.Errorf("worktree[%d].Prunable: expected %v, got %v", i, tt.expected[i].Prunable, wt.Prunable)
+				}
+				if wt.Bare != tt.expected[i].Bare {
+					t.Errorf("worktree[%d].Bare: expected %v, got %v", i, tt.expected[i].Bare, wt.Bare)
+				}
+				if wt.Detached != tt.expected[i].Detached {
+					t.Errorf("worktree[%d].Detached: expected %v, got %v", i, tt.expected[i].Detached, wt.Detached)
+				}
 			}
 		})
 	}
@@ -151,3 +221,33 @@ func TestFindWorktreeByBranch(t *testing.T) {
 		})
 	}
 }
+
+func TestMatchWorktrees(t *testing.T) {
+	worktrees := []Worktree{
+		{Path: "/path/to/repo", Branch: "main"},
+		{Path: "/path/to/wt", Branch: "feature-x"},
+		{Path: "/other/wt", Branch: "feature-y"},
+	}
+
+	if m := MatchWorktrees(worktrees, "/path/to/wt", "/path/to/wt"); len(m) != 1 || m[0].Branch != "feature-x" {
+		t.Errorf("match by path: got %+v", m)
+	}
+	if m := MatchWorktrees(worktrees, "/cwd/repo", "repo"); len(m) != 1 || m[0].Path != "/path/to/repo" {
+		t.Errorf("match by name: got %+v", m)
+	}
+	if m := MatchWorktrees(worktrees, "/cwd/wt", "wt"); len(m) != 2 {
+		t.Errorf("ambiguous name should match 2 worktrees, got %+v", m)
+	}
+	if m := MatchWorktrees(worktrees, "/cwd/none", "none"); len(m) != 0 {
+		t.Errorf("expected no match, got %+v", m)
+	}
+	if m := MatchWorktrees(worktrees, "/cwd/re*", "re*"); len(m) != 1 || m[0].Branch != "main" {
+		t.Errorf("match by name glob: got %+v", m)
+	}
+	if m := MatchWorktrees(worktrees, "/other/*", "/other/*"); len(m) != 1 || m[0].Branch != "feature-y" {
+		t.Errorf("match by path glob: got %+v", m)
+	}
+	if m := MatchWorktrees(worktrees, "/cwd/[", "["); len(m) != 0 {
+		t.Errorf("malformed pattern should not match, got %+v", m)
+	}
+}